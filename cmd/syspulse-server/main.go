@@ -17,6 +17,8 @@ var (
 	metricsService *services.MetricsService
 	wsService      *services.WebSocketService
 	alertService   *services.AlertService
+	eventService   *services.EventService
+	rateLimiter    *handlers.RateLimiter
 )
 
 func main() {
@@ -24,13 +26,25 @@ func main() {
 	log.Printf("📊 Real Time System Monitor")
 	log.Printf("🔌 Web Socket support enabled")
 
+	// loading config
+	cfg := config.Load()
+
 	// initialize services
-	metricsService = services.NewMetricsService()
+	metricsService = services.NewMetricsService(cfg)
 	wsService = services.NewWebSocketService()
 	alertService = services.NewAlertService()
+	eventService = services.NewEventService(cfg.EventHistory)
+
+	if rules, err := services.ParseAlertRules(cfg.AlertRules); err != nil {
+		log.Printf("❌ SYS_PULSE_ALERT_RULES is ignored: %v", err)
+	} else if len(rules) > 0 {
+		alertService.SetRules(rules)
+		log.Printf("🪪 %d custom alert rules loaded", len(rules))
+	}
 
 	handlers.SetMetricService(metricsService)
 	handlers.SetAlertService(alertService)
+	handlers.SetEventService(eventService)
 
 	// web socket service start
 	wsService.Start()
@@ -38,41 +52,65 @@ func main() {
 	//sending out metrics
 	go startMetricBroadcast()
 
-	// loading config
-	cfg := config.Load()
-
-	setupRoutes()
+	rateLimiter = handlers.NewRateLimiter(cfg.RateLimit, cfg.RateBurst)
+	setupRoutes(cfg)
 
 	addr := fmt.Sprintf(":%s", cfg.Port)
 	log.Printf("🌐 server is running on http://localhost%s\n", addr)
 	log.Printf("📱 mode: %s\n", cfg.Environment)
 
-	if err := http.ListenAndServe(addr, nil); err != nil {
+	server := &http.Server{
+		Addr:              addr,
+		ReadHeaderTimeout: 10 * time.Second,
+		MaxHeaderBytes:    64 * 1024,
+		Handler:           handlers.RequestID(http.DefaultServeMux),
+	}
+
+	if err := server.ListenAndServe(); err != nil {
 		log.Fatalf("❌ Failed to start server: %v", err)
 	}
 
 }
 
-func setupRoutes() {
+func setupRoutes(cfg *config.Config) {
 
 	// ─── Static Files ────────────────────────────────────────────────────
 	fs := http.FileServer(http.Dir("web/static"))
 	http.Handle("/static/", http.StripPrefix("/static/", fs))
 
 	// ─── Handlers ────────────────────────────────────────────────────────
-	http.HandleFunc("/api/version", handlers.VersionHandler(version))
-	http.HandleFunc("/api/health", handlers.HealthHandler)
-	http.HandleFunc("/api/metrics", handlers.MetricsHandler)
-	http.HandleFunc("/api/clients", handlers.ClientsHandler(wsService))
-	http.HandleFunc("/api/alerts/history", handlers.AlertHandler)
-	http.HandleFunc("/api/alerts/config", handlers.AlertConfigHandler)
-	http.HandleFunc("/api/alerts/clear", handlers.ClearAlertHandler)
-
-	http.HandleFunc("/api/debug", func(w http.ResponseWriter, r *http.Request) {
-		metrics := metricsService.GetSystemMetrics()
+	api := func(pattern string, handler http.HandlerFunc) {
+		http.HandleFunc(pattern, rateLimiter.Limit(handlers.LimitBody(int64(cfg.MaxBodyBytes), handler)))
+	}
+
+	api("/api/version", handlers.VersionHandler(version))
+	api("/api/health", handlers.HealthHandler)
+	api("/api/metrics", handlers.MetricsHandler)
+	api("/api/clients", handlers.ClientsHandler(wsService))
+	api("/api/alerts/history", handlers.AlertHandler)
+	api("/api/alerts/config", handlers.AlertConfigHandler)
+	api("/api/alerts/clear", handlers.ClearAlertHandler)
+
+	api("/api/debug", func(w http.ResponseWriter, r *http.Request) {
+		metrics := metricsService.GetLatestMetrics()
 		json.NewEncoder(w).Encode(metrics)
 	})
 
+	// ─── API v1 ──────────────────────────────────────────────────────────
+	v1 := handlers.NewV1API(handlers.V1Options{
+		Version:        version,
+		WSService:      wsService,
+		ProcessControl: services.NewProcessControlService(),
+		Limiter:        rateLimiter,
+		MaxBodyBytes:   int64(cfg.MaxBodyBytes),
+		AdminToken:     cfg.AdminToken,
+	})
+	http.Handle("/api/v1/", v1)
+	for _, mount := range v1.UnversionedMounts() {
+		http.Handle(mount, v1)
+		http.Handle(mount+"/", v1)
+	}
+
 	http.HandleFunc("/ws", wsService.HandleConnection)
 
 	// ─── Main Page ───────────────────────────────────────────────────────
@@ -88,9 +126,11 @@ func startMetricBroadcast() { // starting periodically sending metrics
 
 	for range ticker.C {
 		metrics := metricsService.GetSystemMetrics()
+		eventService.AddEvents(metrics.Events)
 
 		alerts := alertService.CheckMetrics(metrics)
 		metrics.Alerts = alerts
+		metrics.Processes = services.TopProcesses(metrics.Processes, cfg.WSProcessLimit)
 
 		wsService.BroadcastMessage(metrics)
 
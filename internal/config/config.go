@@ -6,10 +6,34 @@ import (
 )
 
 type Config struct {
-	Port             string
-	Environment      string
-	UpdateInterval   int
-	AlertThreshholds AlertConfig
+	Port              string
+	Environment       string
+	UpdateInterval    int
+	AlertThreshholds  AlertConfig
+	RateLimit         float64 // api requests per second allowed for one client, 0 disables limiting
+	RateBurst         int     // how many requests a client can make at once
+	MaxBodyBytes      int     // max size of api request body
+	WSProcessLimit    int     // top processes by cpu and by memory sent over websocket, 0 sends all
+	ProcRoot          string  // where procfs is mounted, e.g. /host/proc inside a container
+	SysRoot           string  // where sysfs is mounted, e.g. /host/sys inside a container
+	NormalizeCPU      bool    // per process cpu % is divided by amount of cores
+	AdminToken        string  // bearer token for admin api, admin api is disabled when empty
+	AlertRules        string  // json list of custom alert rules
+	EventHistory      int     // how many events are kept for /api/events
+	ProcessGroups     string  // json list of watched process groups
+	PSICgroups        string  // comma separated globs of cgroups to read pressure of, relative to cgroup root
+	Cgroups           string  // comma separated globs of cgroups to report controller stats of, relative to cgroup root
+	ContainerLimits   bool    // top level cpu and memory are of the own cgroup and its limits instead of the host
+	DiskInclude       string  // comma separated globs of mount points, devices or fstypes to report, all real ones when empty
+	DiskExclude       string  // comma separated globs of mount points, devices or fstypes to skip
+	NetInclude        string  // comma separated globs of network interfaces to report, all when empty
+	NetExclude        string  // comma separated globs of network interfaces to skip
+	StateDir          string  // directory for state kept across restarts, e.g. traffic totals
+	Probes            string  // json list of latency probes, none by default
+	HTTPChecks        string  // json list of http endpoint checks, none by default
+	PublicIPProviders string  // comma separated public ip providers, lookup is disabled when empty
+	PublicIPInterval  int     // seconds between public ip lookups
+	DockerSocket      string  // docker engine api socket, containers are not watched when empty
 }
 
 type AlertConfig struct {
@@ -28,6 +52,30 @@ func Load() *Config {
 			RAM:  getEnvFloat("SYS_PULSE_ALERT_RAM", 85.0),
 			Disk: getEnvFloat("SYS_PULSE_ALERT_DISK", 90.0),
 		},
+		RateLimit:         getEnvFloat("SYS_PULSE_RATE_LIMIT", 10.0),
+		RateBurst:         getEnvInt("SYS_PULSE_RATE_BURST", 20),
+		MaxBodyBytes:      getEnvInt("SYS_PULSE_MAX_BODY_BYTES", 64*1024),
+		WSProcessLimit:    getEnvInt("SYS_PULSE_WS_PROCESS_LIMIT", 10),
+		ProcRoot:          getEnv("SYS_PULSE_PROC_ROOT", "/proc"),
+		SysRoot:           getEnv("SYS_PULSE_SYS_ROOT", "/sys"),
+		NormalizeCPU:      getEnvBool("SYS_PULSE_PROCESS_CPU_NORMALIZE", false),
+		AdminToken:        getEnv("SYS_PULSE_ADMIN_TOKEN", ""),
+		AlertRules:        getEnv("SYS_PULSE_ALERT_RULES", ""),
+		EventHistory:      getEnvInt("SYS_PULSE_EVENT_HISTORY", 1000),
+		ProcessGroups:     getEnv("SYS_PULSE_PROCESS_GROUPS", ""),
+		PSICgroups:        getEnv("SYS_PULSE_PSI_CGROUPS", "*"),
+		Cgroups:           getEnv("SYS_PULSE_CGROUPS", "*"),
+		ContainerLimits:   getEnvBool("SYS_PULSE_CONTAINER_LIMITS", false),
+		DiskInclude:       getEnv("SYS_PULSE_DISK_INCLUDE", ""),
+		DiskExclude:       getEnv("SYS_PULSE_DISK_EXCLUDE", ""),
+		NetInclude:        getEnv("SYS_PULSE_NET_INCLUDE", ""),
+		NetExclude:        getEnv("SYS_PULSE_NET_EXCLUDE", "lo"),
+		StateDir:          getEnv("SYS_PULSE_STATE_DIR", "/var/lib/syspulse"),
+		Probes:            getEnv("SYS_PULSE_PROBES", ""),
+		HTTPChecks:        getEnv("SYS_PULSE_HTTP_CHECKS", ""),
+		PublicIPProviders: getEnv("SYS_PULSE_PUBLIC_IP", ""),
+		PublicIPInterval:  getEnvInt("SYS_PULSE_PUBLIC_IP_INTERVAL", 1800),
+		DockerSocket:      getEnv("SYS_PULSE_DOCKER_SOCKET", ""),
 	}
 	return cfg
 }
@@ -58,3 +106,13 @@ func getEnvFloat(key string, defaultFloat float64) float64 {
 	}
 	return defaultFloat
 }
+
+func getEnvBool(key string, defaultBool bool) bool {
+	if value := os.Getenv(key); value != "" {
+		valueBool, err := strconv.ParseBool(value)
+		if err == nil {
+			return valueBool
+		}
+	}
+	return defaultBool
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"syspulse/internal/models"
 	"syspulse/internal/services"
@@ -10,6 +11,7 @@ import (
 
 var metricsService *services.MetricsService
 var alertsService *services.AlertService
+var eventsService *services.EventService
 
 func SetMetricService(service *services.MetricsService) {
 	metricsService = service
@@ -19,12 +21,19 @@ func SetAlertService(service *services.AlertService) {
 	alertsService = service
 }
 
+func SetEventService(service *services.EventService) {
+	eventsService = service
+}
+
+// HealthHandler keeps the misspelled "timastamp" next to "timestamp" for clients that still read it
 func HealthHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
+	now := time.Now().UTC()
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"service":   "SysPulse Monitor",
 		"status":    "healthy",
-		"timastamp": time.Now().UTC(),
+		"timestamp": now,
+		"timastamp": now,
 		"features":  []string{"WebSocket", "Real-Time", "Metrics"},
 	})
 }
@@ -58,7 +67,7 @@ func MetricsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	metrics := metricsService.GetSystemMetrics()
+	metrics := metricsService.GetLatestMetrics()
 	json.NewEncoder(w).Encode(metrics)
 }
 
@@ -92,11 +101,20 @@ func AlertConfigHandler(w http.ResponseWriter, r *http.Request) {
 	case "POST":
 		var config models.AlertConfig
 		if err := json.NewDecoder(r.Body).Decode(&config); err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				http.Error(w, `{"error":"request body too large"}`, http.StatusRequestEntityTooLarge)
+				return
+			}
 			http.Error(w, `{"error":"Invalid JSON"}`, http.StatusBadRequest)
 			return
 		}
 
-		alertsService.UpdateConfig(config)
+		if err := alertsService.UpdateConfig(config); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
 		json.NewEncoder(w).Encode(map[string]string{"status": "updated"})
 	default:
 		http.Error(w, `{"error":"method not allowed"}`, http.StatusMethodNotAllowed)
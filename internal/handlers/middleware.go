@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RateLimiter keeps one token bucket per client ip
+type RateLimiter struct {
+	mu        sync.Mutex
+	rate      float64 // tokens added per second
+	burst     float64 // bucket capacity
+	clients   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+type tokenBucket struct {
+	tokens   float64
+	lastSeen time.Time
+}
+
+const bucketIdleTimeout = 5 * time.Minute // idle buckets are full anyway, so they can be dropped
+
+func NewRateLimiter(rate float64, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &RateLimiter{
+		rate:      rate,
+		burst:     float64(burst),
+		clients:   make(map[string]*tokenBucket),
+		lastSweep: time.Now(),
+	}
+}
+
+// Allow takes one token from client bucket, returns false and time to wait if bucket is empty
+func (rl *RateLimiter) Allow(client string) (bool, time.Duration) {
+	if rl.rate <= 0 {
+		return true, 0
+	}
+
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	now := time.Now()
+	rl.sweep(now)
+
+	bucket, ok := rl.clients[client]
+	if !ok {
+		bucket = &tokenBucket{tokens: rl.burst, lastSeen: now}
+		rl.clients[client] = bucket
+	}
+
+	bucket.tokens = math.Min(rl.burst, bucket.tokens+now.Sub(bucket.lastSeen).Seconds()*rl.rate)
+	bucket.lastSeen = now
+
+	if bucket.tokens < 1 {
+		wait := time.Duration((1 - bucket.tokens) / rl.rate * float64(time.Second))
+		return false, wait
+	}
+
+	bucket.tokens--
+	return true, 0
+}
+
+func (rl *RateLimiter) sweep(now time.Time) { // removing buckets of clients that are gone
+	if now.Sub(rl.lastSweep) < time.Minute {
+		return
+	}
+	for client, bucket := range rl.clients {
+		if now.Sub(bucket.lastSeen) > bucketIdleTimeout {
+			delete(rl.clients, client)
+		}
+	}
+	rl.lastSweep = now
+}
+
+func (rl *RateLimiter) Limit(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		allowed, wait := rl.Allow(clientIP(r))
+		if !allowed {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			http.Error(w, `{"error":"rate limit exceeded"}`, http.StatusTooManyRequests)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// LimitBody cuts request body to maxBytes, handlers get *http.MaxBytesError when reading more
+func LimitBody(maxBytes int64, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if maxBytes > 0 && r.Body != nil {
+			if r.ContentLength > maxBytes {
+				w.Header().Set("Content-Type", "application/json")
+				http.Error(w, `{"error":"request body too large"}`, http.StatusRequestEntityTooLarge)
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+		}
+		next(w, r)
+	}
+}
+
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,227 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strings"
+	"syspulse/internal/models"
+	"time"
+)
+
+// openapi document is generated from route table and models, so it can't drift from the code
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	pathWildcardRe = regexp.MustCompile(`\{([A-Za-z0-9_]+)\.{0,3}\}`)
+)
+
+type openAPIGenerator struct {
+	schemas map[string]any
+}
+
+func (api *V1API) buildOpenAPI() []byte {
+	gen := &openAPIGenerator{schemas: make(map[string]any)}
+	errorRef := gen.schemaRef(reflect.TypeOf(models.ErrorResponse{}))
+
+	paths := make(map[string]map[string]any)
+	for _, route := range api.routes {
+		path := v1Prefix + pathWildcardRe.ReplaceAllString(route.Path, "{$1}")
+		if paths[path] == nil {
+			paths[path] = make(map[string]any)
+		}
+
+		var parameters []any
+		for _, match := range pathWildcardRe.FindAllStringSubmatch(route.Path, -1) {
+			parameters = append(parameters, map[string]any{
+				"name":     match[1],
+				"in":       "path",
+				"required": true,
+				"schema":   map[string]any{"type": "string"},
+			})
+		}
+		for _, param := range route.Params {
+			parameters = append(parameters, map[string]any{
+				"name":        param.Name,
+				"in":          "query",
+				"description": param.Description,
+				"schema":      map[string]any{"type": param.Type},
+			})
+		}
+
+		operation := map[string]any{
+			"summary":     route.Summary,
+			"operationId": operationID(route),
+			"responses": map[string]any{
+				"200": map[string]any{
+					"description": "OK",
+					"content": map[string]any{
+						"application/json": map[string]any{"schema": gen.schemaRef(reflect.TypeOf(route.Response))},
+					},
+				},
+				"default": map[string]any{
+					"description": "Error envelope",
+					"content": map[string]any{
+						"application/json": map[string]any{"schema": errorRef},
+					},
+				},
+			},
+		}
+		if len(parameters) > 0 {
+			operation["parameters"] = parameters
+		}
+		if route.Admin {
+			operation["security"] = []any{map[string]any{"adminToken": []any{}}}
+		}
+		if route.Request != nil {
+			operation["requestBody"] = map[string]any{
+				"required": true,
+				"content": map[string]any{
+					"application/json": map[string]any{"schema": gen.schemaRef(reflect.TypeOf(route.Request))},
+				},
+			}
+		}
+
+		paths[path][strings.ToLower(route.Method)] = operation
+	}
+
+	doc := map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":   "SysPulse Monitor API",
+			"version": api.opts.Version,
+		},
+		"servers": []any{map[string]any{"url": "/"}},
+		"paths":   paths,
+		"components": map[string]any{
+			"schemas": gen.schemas,
+			"securitySchemes": map[string]any{
+				"adminToken": map[string]any{"type": "http", "scheme": "bearer"},
+			},
+		},
+	}
+
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		panic("openapi: " + err.Error()) // only possible with broken route table
+	}
+	return data
+}
+
+func operationID(route apiRoute) string {
+	id := strings.ToLower(route.Method)
+	for _, part := range strings.FieldsFunc(route.Path, func(r rune) bool { return r == '/' || r == '{' || r == '}' || r == '.' || r == '_' }) {
+		id += strings.ToUpper(part[:1]) + part[1:]
+	}
+	return id
+}
+
+// schemaRef returns schema of t, named structs are put to components and referenced
+func (gen *openAPIGenerator) schemaRef(t reflect.Type) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if t.Kind() == reflect.Struct && t != timeType && t.Name() != "" {
+		if _, ok := gen.schemas[t.Name()]; !ok {
+			gen.schemas[t.Name()] = map[string]any{} // placeholder for recursive types
+			gen.schemas[t.Name()] = gen.structSchema(t)
+		}
+		return map[string]any{"$ref": "#/components/schemas/" + t.Name()}
+	}
+	return gen.schema(t)
+}
+
+func (gen *openAPIGenerator) schema(t reflect.Type) map[string]any {
+	if t == timeType {
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		return map[string]any{"type": "integer", "format": "int32"}
+	case reflect.Int, reflect.Int64:
+		return map[string]any{"type": "integer", "format": "int64"}
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint, reflect.Uint64:
+		return map[string]any{"type": "integer", "minimum": 0}
+	case reflect.Float32:
+		return map[string]any{"type": "number", "format": "float"}
+	case reflect.Float64:
+		return map[string]any{"type": "number", "format": "double"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "format": "byte"}
+		}
+		return map[string]any{"type": "array", "items": gen.schemaRef(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": gen.schemaRef(t.Elem())}
+	case reflect.Pointer:
+		return gen.schemaRef(t)
+	case reflect.Struct:
+		return gen.structSchema(t)
+	default:
+		return map[string]any{}
+	}
+}
+
+func (gen *openAPIGenerator) structSchema(t reflect.Type) map[string]any {
+	properties := make(map[string]any)
+	var required []string
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, omitEmpty := jsonFieldName(field)
+		if name == "-" {
+			continue
+		}
+
+		schema := gen.schemaRef(field.Type)
+		if field.Type.Kind() == reflect.Pointer {
+			schema = map[string]any{"allOf": []any{schema}, "nullable": true}
+		}
+		properties[name] = schema
+		if !omitEmpty && field.Type.Kind() != reflect.Pointer {
+			required = append(required, name)
+		}
+	}
+
+	schema := map[string]any{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+func jsonFieldName(field reflect.StructField) (string, bool) {
+	tag := field.Tag.Get("json")
+	if tag == "" {
+		return field.Name, false
+	}
+
+	parts := strings.Split(tag, ",")
+	name := parts[0]
+	if name == "" {
+		name = field.Name
+	}
+	omitEmpty := false
+	for _, opt := range parts[1:] {
+		if opt == "omitempty" || opt == "omitzero" {
+			omitEmpty = true
+		}
+	}
+	return name, omitEmpty
+}
+
+func (api *V1API) serveOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(api.openapi)
+}
@@ -0,0 +1,748 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"syspulse/internal/models"
+	"syspulse/internal/services"
+	"time"
+)
+
+const v1Prefix = "/api/v1"
+
+// every admin action is written here, whether it succeeded or not
+var auditLog = log.New(os.Stderr, "🛡️ audit: ", log.LstdFlags)
+
+// error codes of api v1 envelope
+const (
+	codeBadRequest       = "bad_request"
+	codeNotFound         = "not_found"
+	codeUnauthorized     = "unauthorized"
+	codeForbidden        = "forbidden"
+	codeConflict         = "conflict"
+	codeNotImplemented   = "not_implemented"
+	codeMethodNotAllowed = "method_not_allowed"
+	codePayloadTooLarge  = "payload_too_large"
+	codeRateLimited      = "rate_limited"
+	codeUnavailable      = "service_unavailable"
+	codeInternal         = "internal_error"
+)
+
+type V1Options struct {
+	Version        string
+	WSService      *services.WebSocketService
+	ProcessControl *services.ProcessControlService
+	Limiter        *RateLimiter
+	MaxBodyBytes   int64
+	AdminToken     string // bearer token for admin endpoints, admin endpoints are disabled when empty
+}
+
+// apiRoute describes one v1 endpoint, same table is used for routing and for openapi document
+type apiRoute struct {
+	Method      string
+	Path        string // path after /api/v1, may contain {wildcards}
+	Summary     string
+	Params      []apiParam // query parameters
+	Request     any        // request body model, nil if endpoint has no body
+	Response    any        // response body model
+	Unversioned bool       // also serve route under /api without version
+	Admin       bool       // needs admin bearer token
+	handler     http.HandlerFunc
+}
+
+type apiParam struct {
+	Name        string
+	Type        string // openapi type: string, integer, number, boolean
+	Description string
+}
+
+type V1API struct {
+	mux     *http.ServeMux
+	routes  []apiRoute
+	opts    V1Options
+	openapi []byte
+}
+
+func NewV1API(opts V1Options) *V1API {
+	api := &V1API{
+		mux:  http.NewServeMux(),
+		opts: opts,
+	}
+
+	api.handle(apiRoute{Method: "GET", Path: "/health", Summary: "Service health check",
+		Response: models.HealthStatus{}, handler: api.health})
+	api.handle(apiRoute{Method: "GET", Path: "/version", Summary: "Application version",
+		Response: models.VersionInfo{}, handler: api.version})
+	api.handle(apiRoute{Method: "GET", Path: "/metrics", Summary: "Latest system metrics snapshot",
+		Response: models.SystemMetrics{}, handler: api.metrics})
+	api.handle(apiRoute{Method: "GET", Path: "/clients", Summary: "Connected WebSocket clients",
+		Response: models.ClientsInfo{}, handler: api.clients})
+	api.handle(apiRoute{Method: "GET", Path: "/processes", Summary: "Filtered, sorted and paginated process list",
+		Params: []apiParam{
+			{Name: "sort", Type: "string", Description: "field to sort by: pid, process, cpu_percent, memory_percent, memory_rss, threads, createtime, user, status"},
+			{Name: "order", Type: "string", Description: "asc or desc, desc by default"},
+			{Name: "limit", Type: "integer", Description: "page size, 50 by default, 1000 max"},
+			{Name: "cursor", Type: "string", Description: "next_cursor from previous page"},
+			{Name: "user", Type: "string", Description: "exact user name"},
+			{Name: "name", Type: "string", Description: "exact process name"},
+			{Name: "name~", Type: "string", Description: "regular expression for process name, used as name~=java"},
+			{Name: "status", Type: "string", Description: "process status, word or letter, e.g. running or R"},
+		},
+		Response: models.ProcessList{}, Unversioned: true, handler: api.processes})
+	api.handle(apiRoute{Method: "GET", Path: "/processes/tree", Summary: "Process tree with subtree usage totals",
+		Params: []apiParam{
+			{Name: "group_by", Type: "string", Description: "collapse tree: name folds same-named children, unit and cgroup give flat groups"},
+		},
+		Response: models.ProcessTree{}, Unversioned: true, handler: api.processTree})
+	api.handle(apiRoute{Method: "GET", Path: "/processes/groups", Summary: "Usage and health of watched process groups",
+		Response: []models.ProcessGroup{}, Unversioned: true, handler: api.processGroups})
+	api.handle(apiRoute{Method: "GET", Path: "/processes/{pid}", Summary: "Details of one process: IO, descriptors, sockets, memory maps",
+		Params: []apiParam{
+			{Name: "fd_limit", Type: "integer", Description: "how many open descriptors to list, 100 by default"},
+		},
+		Response: models.ProcessDetails{}, Unversioned: true, handler: api.processDetails})
+	api.handle(apiRoute{Method: "POST", Path: "/processes/{pid}/signal", Summary: "Send signal to process (admin)",
+		Request: models.ProcessSignalRequest{}, Response: models.ProcessActionResult{},
+		Unversioned: true, Admin: true, handler: api.signalProcess})
+	api.handle(apiRoute{Method: "POST", Path: "/processes/{pid}/priority", Summary: "Change nice value and IO priority of process (admin)",
+		Request: models.ProcessPriorityRequest{}, Response: models.ProcessActionResult{},
+		Unversioned: true, Admin: true, handler: api.setProcessPriority})
+	api.handle(apiRoute{Method: "POST", Path: "/processes/{pid}/affinity", Summary: "Set CPU affinity of process (admin)",
+		Request: models.ProcessAffinityRequest{}, Response: models.ProcessActionResult{},
+		Unversioned: true, Admin: true, handler: api.setProcessAffinity})
+	api.handle(apiRoute{Method: "GET", Path: "/events", Summary: "Host event history, newest first",
+		Params: []apiParam{
+			{Name: "type", Type: "string", Description: "event type, e.g. process_started or process_exited"},
+			{Name: "since", Type: "string", Description: "RFC 3339 time, only newer events are returned"},
+			{Name: "limit", Type: "integer", Description: "max events, 100 by default"},
+		},
+		Response: models.EventList{}, Unversioned: true, handler: api.events})
+	api.handle(apiRoute{Method: "GET", Path: "/connections", Summary: "Live TCP and UDP sockets with owning processes",
+		Params: []apiParam{
+			{Name: "port", Type: "integer", Description: "local or remote port"},
+			{Name: "pid", Type: "integer", Description: "owning process id"},
+			{Name: "process", Type: "string", Description: "exact name of owning process"},
+			{Name: "state", Type: "string", Description: "tcp state, e.g. ESTABLISHED, LISTEN or TIME_WAIT"},
+			{Name: "protocol", Type: "string", Description: "tcp, tcp6, udp or udp6, tcp and udp match both families"},
+			{Name: "limit", Type: "integer", Description: "max connections, 500 by default, 10000 max"},
+		},
+		Response: models.ConnectionList{}, Unversioned: true, handler: api.connections})
+	api.handle(apiRoute{Method: "GET", Path: "/containers", Summary: "Docker containers with usage of running ones",
+		Response: []models.Container{}, handler: api.containers})
+	api.handle(apiRoute{Method: "GET", Path: "/network/traffic", Summary: "Traffic totals and daily and monthly traffic per interface",
+		Response: models.NetworkTraffic{}, handler: api.networkTraffic})
+	api.handle(apiRoute{Method: "GET", Path: "/alerts/history", Summary: "Alert history",
+		Response: models.AlertHistory{}, handler: api.alertHistory})
+	api.handle(apiRoute{Method: "GET", Path: "/alerts/config", Summary: "Alert configuration",
+		Response: models.AlertConfig{}, handler: api.alertConfig})
+	api.handle(apiRoute{Method: "PUT", Path: "/alerts/config", Summary: "Update alert configuration",
+		Request: models.AlertConfig{}, Response: models.StatusResponse{}, handler: api.updateAlertConfig})
+	api.handle(apiRoute{Method: "POST", Path: "/alerts/clear", Summary: "Clear alert history",
+		Response: models.StatusResponse{}, handler: api.clearAlerts})
+
+	api.openapi = api.buildOpenAPI()
+	api.mux.HandleFunc("GET "+v1Prefix+"/openapi.json", api.serveOpenAPI)
+
+	api.registerFallbacks()
+	return api
+}
+
+func (api *V1API) handle(route apiRoute) {
+	handler := route.handler
+	if route.Admin {
+		handler = api.requireAdmin(handler)
+	}
+
+	api.routes = append(api.routes, route)
+	api.mux.HandleFunc(route.Method+" "+v1Prefix+route.Path, handler)
+	if route.Unversioned {
+		api.mux.HandleFunc(route.Method+" /api"+route.Path, handler)
+	}
+}
+
+func (api *V1API) requireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if api.opts.AdminToken == "" {
+			writeError(w, r, http.StatusForbidden, codeForbidden, "admin endpoints are disabled, set SYS_PULSE_ADMIN_TOKEN to enable them")
+			return
+		}
+
+		if !api.isAdmin(r) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="syspulse"`)
+			writeError(w, r, http.StatusUnauthorized, codeUnauthorized, "valid admin bearer token is required")
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (api *V1API) isAdmin(r *http.Request) bool {
+	if api.opts.AdminToken == "" {
+		return false
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(token), []byte(api.opts.AdminToken)) == 1
+}
+
+// registerFallbacks adds a pattern for every method a path does not serve, so wrong methods and unknown paths
+// get an envelope instead of plain text. A method-less pattern would conflict with wildcard routes,
+// e.g. "/processes/tree" and "GET /processes/{pid}" overlap and neither is more specific
+func (api *V1API) registerFallbacks() {
+	allowed := make(map[string][]string)
+	var paths []string
+	for _, route := range api.routes {
+		prefixes := []string{v1Prefix}
+		if route.Unversioned {
+			prefixes = append(prefixes, "/api")
+		}
+		for _, prefix := range prefixes {
+			path := prefix + route.Path
+			if _, ok := allowed[path]; !ok {
+				paths = append(paths, path)
+			}
+			allowed[path] = append(allowed[path], route.Method)
+		}
+	}
+	allowed[v1Prefix+"/openapi.json"] = []string{"GET"}
+	paths = append(paths, v1Prefix+"/openapi.json")
+
+	for _, path := range paths {
+		methods := strings.Join(sortedMethods(allowed[path]), ", ")
+		handler := func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Allow", methods)
+			writeError(w, r, http.StatusMethodNotAllowed, codeMethodNotAllowed,
+				fmt.Sprintf("method %s is not allowed, use %s", r.Method, methods))
+		}
+		for _, method := range fallbackMethods {
+			if !slices.Contains(allowed[path], method) {
+				api.mux.HandleFunc(method+" "+path, handler)
+			}
+		}
+	}
+
+	notFound := func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, r, http.StatusNotFound, codeNotFound, "no such endpoint: "+r.URL.Path)
+	}
+	api.mux.HandleFunc(v1Prefix+"/", notFound)
+	for _, mount := range api.UnversionedMounts() {
+		api.mux.HandleFunc(mount+"/", notFound)
+	}
+}
+
+// UnversionedMounts returns the paths under /api the api serves without version, e.g. /api/processes.
+// They have to be routed to the api together with their subtrees
+func (api *V1API) UnversionedMounts() []string {
+	var mounts []string
+	for _, route := range api.routes {
+		if !route.Unversioned {
+			continue
+		}
+		segment, _, _ := strings.Cut(strings.TrimPrefix(route.Path, "/"), "/")
+		if mount := "/api/" + segment; !slices.Contains(mounts, mount) {
+			mounts = append(mounts, mount)
+		}
+	}
+	return mounts
+}
+
+// fallbackMethods get 405 on known paths, GET patterns also match HEAD
+var fallbackMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
+
+func (api *V1API) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if api.opts.Limiter != nil {
+		if allowed, wait := api.opts.Limiter.Allow(clientIP(r)); !allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			writeError(w, r, http.StatusTooManyRequests, codeRateLimited, "rate limit exceeded")
+			return
+		}
+	}
+	api.mux.ServeHTTP(w, r)
+}
+
+// ─── Handlers ────────────────────────────────────────────────────────────────
+
+func (api *V1API) health(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, models.HealthStatus{
+		Service:   "SysPulse Monitor",
+		Status:    "healthy",
+		Timestamp: time.Now().UTC(),
+		Features:  []string{"WebSocket", "Real-Time", "Metrics"},
+	})
+}
+
+func (api *V1API) version(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, models.VersionInfo{
+		Service: "SysPulse Monitor",
+		Version: api.opts.Version,
+	})
+}
+
+func (api *V1API) metrics(w http.ResponseWriter, r *http.Request) {
+	if metricsService == nil {
+		writeError(w, r, http.StatusServiceUnavailable, codeUnavailable, "metrics service not initialized")
+		return
+	}
+	writeJSON(w, http.StatusOK, metricsService.GetLatestMetrics())
+}
+
+func (api *V1API) processes(w http.ResponseWriter, r *http.Request) {
+	if metricsService == nil {
+		writeError(w, r, http.StatusServiceUnavailable, codeUnavailable, "metrics service not initialized")
+		return
+	}
+
+	query, err := parseProcessQuery(r.URL.Query())
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, codeBadRequest, err.Error())
+		return
+	}
+
+	list, err := metricsService.QueryProcesses(query)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, codeBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+func (api *V1API) processTree(w http.ResponseWriter, r *http.Request) {
+	if metricsService == nil {
+		writeError(w, r, http.StatusServiceUnavailable, codeUnavailable, "metrics service not initialized")
+		return
+	}
+
+	tree, err := metricsService.ProcessTree(r.URL.Query().Get("group_by"))
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, codeBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, tree)
+}
+
+func (api *V1API) connections(w http.ResponseWriter, r *http.Request) {
+	if metricsService == nil {
+		writeError(w, r, http.StatusServiceUnavailable, codeUnavailable, "metrics service not initialized")
+		return
+	}
+
+	query, err := parseConnectionQuery(r.URL.Query())
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, codeBadRequest, err.Error())
+		return
+	}
+
+	list, err := metricsService.QueryConnections(query)
+	if err != nil {
+		writeError(w, r, http.StatusServiceUnavailable, codeUnavailable, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+func (api *V1API) networkTraffic(w http.ResponseWriter, r *http.Request) {
+	if metricsService == nil {
+		writeError(w, r, http.StatusServiceUnavailable, codeUnavailable, "metrics service not initialized")
+		return
+	}
+	writeJSON(w, http.StatusOK, metricsService.NetworkTraffic())
+}
+
+func (api *V1API) containers(w http.ResponseWriter, r *http.Request) {
+	if metricsService == nil {
+		writeError(w, r, http.StatusServiceUnavailable, codeUnavailable, "metrics service not initialized")
+		return
+	}
+
+	containers := metricsService.GetLatestMetrics().Containers
+	if containers == nil {
+		containers = []models.Container{}
+	}
+	writeJSON(w, http.StatusOK, containers)
+}
+
+func (api *V1API) processGroups(w http.ResponseWriter, r *http.Request) {
+	if metricsService == nil {
+		writeError(w, r, http.StatusServiceUnavailable, codeUnavailable, "metrics service not initialized")
+		return
+	}
+
+	groups := metricsService.GetLatestMetrics().ProcessGroups
+	if groups == nil {
+		groups = []models.ProcessGroup{}
+	}
+	writeJSON(w, http.StatusOK, groups)
+}
+
+func (api *V1API) processDetails(w http.ResponseWriter, r *http.Request) {
+	if metricsService == nil {
+		writeError(w, r, http.StatusServiceUnavailable, codeUnavailable, "metrics service not initialized")
+		return
+	}
+
+	pid, err := strconv.ParseInt(r.PathValue("pid"), 10, 32)
+	if err != nil || pid <= 0 {
+		writeError(w, r, http.StatusBadRequest, codeBadRequest, "pid must be a positive number")
+		return
+	}
+
+	opts := services.ProcessDetailsOptions{Environment: api.isAdmin(r)}
+	if limit := r.URL.Query().Get("fd_limit"); limit != "" {
+		if opts.FDLimit, err = strconv.Atoi(limit); err != nil || opts.FDLimit < 1 {
+			writeError(w, r, http.StatusBadRequest, codeBadRequest, "fd_limit must be a positive number")
+			return
+		}
+	}
+
+	details, err := metricsService.ProcessDetails(int32(pid), opts)
+	if errors.Is(err, services.ErrProcessNotFound) {
+		writeError(w, r, http.StatusNotFound, codeNotFound, fmt.Sprintf("process %d not found", pid))
+		return
+	}
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, codeInternal, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, details)
+}
+
+func (api *V1API) signalProcess(w http.ResponseWriter, r *http.Request) {
+	var req models.ProcessSignalRequest
+	pid, ok := api.processAction(w, r, &req)
+	if !ok {
+		return
+	}
+	result, err := api.opts.ProcessControl.Signal(pid, req)
+	api.finishProcessAction(w, r, result, err, fmt.Sprintf("signal=%s create_time=%d", req.Signal, req.CreateTime))
+}
+
+func (api *V1API) setProcessPriority(w http.ResponseWriter, r *http.Request) {
+	var req models.ProcessPriorityRequest
+	pid, ok := api.processAction(w, r, &req)
+	if !ok {
+		return
+	}
+	result, err := api.opts.ProcessControl.SetPriority(pid, req)
+
+	params := fmt.Sprintf("create_time=%d", req.CreateTime)
+	if req.Nice != nil {
+		params += fmt.Sprintf(" nice=%d", *req.Nice)
+	}
+	if req.IOClass != "" {
+		params += " io_class=" + req.IOClass
+	}
+	if req.IOPriority != nil {
+		params += fmt.Sprintf(" io_priority=%d", *req.IOPriority)
+	}
+	api.finishProcessAction(w, r, result, err, params)
+}
+
+func (api *V1API) setProcessAffinity(w http.ResponseWriter, r *http.Request) {
+	var req models.ProcessAffinityRequest
+	pid, ok := api.processAction(w, r, &req)
+	if !ok {
+		return
+	}
+	result, err := api.opts.ProcessControl.SetAffinity(pid, req)
+	api.finishProcessAction(w, r, result, err, fmt.Sprintf("cpus=%v create_time=%d", req.CPUs, req.CreateTime))
+}
+
+// processAction parses pid from path and request body, shared by all process control endpoints
+func (api *V1API) processAction(w http.ResponseWriter, r *http.Request, req any) (int32, bool) {
+	if api.opts.ProcessControl == nil {
+		writeError(w, r, http.StatusServiceUnavailable, codeUnavailable, "process control service not initialized")
+		return 0, false
+	}
+
+	pid, err := strconv.ParseInt(r.PathValue("pid"), 10, 32)
+	if err != nil || pid <= 0 {
+		writeError(w, r, http.StatusBadRequest, codeBadRequest, "pid must be a positive number")
+		return 0, false
+	}
+
+	if !api.decodeJSON(w, r, req) {
+		return 0, false
+	}
+	return int32(pid), true
+}
+
+func (api *V1API) finishProcessAction(w http.ResponseWriter, r *http.Request, result models.ProcessActionResult, err error, params string) {
+	outcome := "ok"
+	if err != nil {
+		outcome = "error: " + err.Error()
+	}
+	auditLog.Printf("client=%s request_id=%s action=%s pid=%d %s dry_run=%v result=%q",
+		clientIP(r), RequestIDFromContext(r.Context()), result.Action, result.PID, params, result.DryRun, outcome)
+
+	switch {
+	case err == nil:
+		writeJSON(w, http.StatusOK, result)
+	case errors.Is(err, services.ErrProcessNotFound):
+		writeError(w, r, http.StatusNotFound, codeNotFound, err.Error())
+	case errors.Is(err, services.ErrProcessReused):
+		writeError(w, r, http.StatusConflict, codeConflict, err.Error())
+	case errors.Is(err, services.ErrInvalidAction):
+		writeError(w, r, http.StatusBadRequest, codeBadRequest, err.Error())
+	case errors.Is(err, services.ErrNotPermitted):
+		writeError(w, r, http.StatusForbidden, codeForbidden, err.Error())
+	case errors.Is(err, errors.ErrUnsupported):
+		writeError(w, r, http.StatusNotImplemented, codeNotImplemented, err.Error())
+	default:
+		writeError(w, r, http.StatusInternalServerError, codeInternal, err.Error())
+	}
+}
+
+func parseConnectionQuery(values url.Values) (services.ConnectionQuery, error) {
+	query := services.ConnectionQuery{
+		Process:  values.Get("process"),
+		State:    values.Get("state"),
+		Protocol: values.Get("protocol"),
+	}
+
+	if port := values.Get("port"); port != "" {
+		n, err := strconv.ParseUint(port, 10, 16)
+		if err != nil || n == 0 {
+			return query, fmt.Errorf("port must be a number from 1 to 65535")
+		}
+		query.Port = uint32(n)
+	}
+	if pid := values.Get("pid"); pid != "" {
+		n, err := strconv.ParseInt(pid, 10, 32)
+		if err != nil || n < 1 {
+			return query, fmt.Errorf("pid must be a positive number")
+		}
+		query.PID = int32(n)
+	}
+	switch query.Protocol {
+	case "", "tcp", "tcp6", "udp", "udp6":
+	default:
+		return query, fmt.Errorf("protocol must be tcp, tcp6, udp or udp6")
+	}
+	if limit := values.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 {
+			return query, fmt.Errorf("limit must be a positive number")
+		}
+		query.Limit = n
+	}
+	return query, nil
+}
+
+func parseProcessQuery(values url.Values) (services.ProcessQuery, error) {
+	query := services.ProcessQuery{
+		Sort:   values.Get("sort"),
+		Desc:   true,
+		Cursor: values.Get("cursor"),
+		User:   values.Get("user"),
+		Name:   values.Get("name"),
+		Status: values.Get("status"),
+	}
+
+	if query.Sort == "name" {
+		query.Sort = "process"
+	}
+	if query.Sort != "" && !services.IsProcessSortField(query.Sort) {
+		return query, fmt.Errorf("unknown sort field %q", query.Sort)
+	}
+
+	switch values.Get("order") {
+	case "", "desc":
+	case "asc":
+		query.Desc = false
+	default:
+		return query, fmt.Errorf("order must be asc or desc")
+	}
+
+	if limit := values.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 {
+			return query, fmt.Errorf("limit must be a positive number")
+		}
+		query.Limit = n
+	}
+
+	if pattern := values.Get("name~"); pattern != "" {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return query, fmt.Errorf("invalid name~ pattern: %v", err)
+		}
+		query.NameMatch = re
+	}
+
+	return query, nil
+}
+
+func (api *V1API) clients(w http.ResponseWriter, r *http.Request) {
+	if api.opts.WSService == nil {
+		writeError(w, r, http.StatusServiceUnavailable, codeUnavailable, "websocket service not initialized")
+		return
+	}
+	writeJSON(w, http.StatusOK, models.ClientsInfo{
+		ConnectedClients: api.opts.WSService.GetConnectedClientCount(),
+		Timestamp:        time.Now().UTC(),
+	})
+}
+
+func (api *V1API) events(w http.ResponseWriter, r *http.Request) {
+	if eventsService == nil {
+		writeError(w, r, http.StatusServiceUnavailable, codeUnavailable, "event service not initialized")
+		return
+	}
+
+	query := r.URL.Query()
+	var since time.Time
+	if value := query.Get("since"); value != "" {
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, codeBadRequest, "since must be RFC 3339 time")
+			return
+		}
+		since = t
+	}
+	limit := 0
+	if value := query.Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			writeError(w, r, http.StatusBadRequest, codeBadRequest, "limit must be a positive integer")
+			return
+		}
+		limit = n
+	}
+
+	writeJSON(w, http.StatusOK, eventsService.GetEvents(query.Get("type"), since, limit))
+}
+
+func (api *V1API) alertHistory(w http.ResponseWriter, r *http.Request) {
+	if alertsService == nil {
+		writeError(w, r, http.StatusServiceUnavailable, codeUnavailable, "alert service not initialized")
+		return
+	}
+	writeJSON(w, http.StatusOK, alertsService.GetAlertHistory())
+}
+
+func (api *V1API) alertConfig(w http.ResponseWriter, r *http.Request) {
+	if alertsService == nil {
+		writeError(w, r, http.StatusServiceUnavailable, codeUnavailable, "alert service not initialized")
+		return
+	}
+	writeJSON(w, http.StatusOK, alertsService.GetConfig())
+}
+
+func (api *V1API) updateAlertConfig(w http.ResponseWriter, r *http.Request) {
+	if alertsService == nil {
+		writeError(w, r, http.StatusServiceUnavailable, codeUnavailable, "alert service not initialized")
+		return
+	}
+
+	var config models.AlertConfig
+	if !api.decodeJSON(w, r, &config) {
+		return
+	}
+
+	if err := alertsService.UpdateConfig(config); err != nil {
+		writeError(w, r, http.StatusBadRequest, codeBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, models.StatusResponse{Status: "updated"})
+}
+
+func (api *V1API) clearAlerts(w http.ResponseWriter, r *http.Request) {
+	if alertsService == nil {
+		writeError(w, r, http.StatusServiceUnavailable, codeUnavailable, "alert service not initialized")
+		return
+	}
+
+	alertsService.ClearHistory()
+	writeJSON(w, http.StatusOK, models.StatusResponse{Status: "cleared"})
+}
+
+// ─── Helpers ─────────────────────────────────────────────────────────────────
+
+// decodeJSON reads body into dst, writes error response and returns false on failure
+func (api *V1API) decodeJSON(w http.ResponseWriter, r *http.Request, dst any) bool {
+	if api.opts.MaxBodyBytes > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, api.opts.MaxBodyBytes)
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(dst); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			writeError(w, r, http.StatusRequestEntityTooLarge, codePayloadTooLarge,
+				fmt.Sprintf("request body is larger than %d bytes", maxBytesErr.Limit))
+			return false
+		}
+		writeError(w, r, http.StatusBadRequest, codeBadRequest, "invalid JSON: "+err.Error())
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, r *http.Request, status int, code, message string) {
+	writeJSON(w, status, models.ErrorResponse{
+		Error: models.APIError{
+			Code:      code,
+			Message:   message,
+			RequestID: RequestIDFromContext(r.Context()),
+		},
+	})
+}
+
+func sortedMethods(methods []string) []string {
+	sorted := append([]string(nil), methods...)
+	sort.Strings(sorted)
+	return sorted
+}
+
+// ─── Request ID ──────────────────────────────────────────────────────────────
+
+type requestIDKey struct{}
+
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// RequestID takes X-Request-ID from client or generates a new one, and returns it in response header
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-ID")
+		if !validRequestID.MatchString(id) {
+			id = newRequestID()
+		}
+
+		w.Header().Set("X-Request-ID", id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id)))
+	})
+}
+
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+func newRequestID() string {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return hex.EncodeToString(buf)
+}
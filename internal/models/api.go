@@ -0,0 +1,65 @@
+package models
+
+import (
+	"time"
+)
+
+// every api v1 error is wrapped in this envelope
+type ErrorResponse struct {
+	Error APIError `json:"error"`
+}
+
+type APIError struct {
+	Code      string `json:"code"`                 // machine readable code, e.g. not_found
+	Message   string `json:"message"`              // human readable text
+	RequestID string `json:"request_id,omitempty"` // same as X-Request-ID response header
+}
+
+type HealthStatus struct {
+	Service   string    `json:"service"`
+	Status    string    `json:"status"`
+	Timestamp time.Time `json:"timestamp"`
+	Features  []string  `json:"features"`
+}
+
+type VersionInfo struct {
+	Service string `json:"service"`
+	Version string `json:"version"`
+}
+
+type ClientsInfo struct {
+	ConnectedClients int       `json:"connected_clients"`
+	Timestamp        time.Time `json:"timestamp"`
+}
+
+type StatusResponse struct {
+	Status string `json:"status"` // updated, cleared, etc.
+}
+
+// process control requests, create_time must match the process to protect from pid reuse
+type ProcessSignalRequest struct {
+	CreateTime int64  `json:"create_time"` // same as createtime of ProcessInfo
+	Signal     string `json:"signal"`      // TERM, KILL, HUP, STOP or CONT
+	DryRun     bool   `json:"dry_run,omitempty"`
+}
+
+type ProcessPriorityRequest struct {
+	CreateTime int64  `json:"create_time"`
+	Nice       *int   `json:"nice,omitempty"`        // -20..19
+	IOClass    string `json:"io_class,omitempty"`    // realtime, best-effort or idle
+	IOPriority *int   `json:"io_priority,omitempty"` // 0..7, used with realtime and best-effort
+	DryRun     bool   `json:"dry_run,omitempty"`
+}
+
+type ProcessAffinityRequest struct {
+	CreateTime int64 `json:"create_time"`
+	CPUs       []int `json:"cpus"` // logical cpu numbers
+	DryRun     bool  `json:"dry_run,omitempty"`
+}
+
+type ProcessActionResult struct {
+	PID     int32  `json:"pid"`
+	Action  string `json:"action"` // signal, priority or affinity
+	DryRun  bool   `json:"dry_run"`
+	Message string `json:"message"`
+}
@@ -5,44 +5,165 @@ import (
 )
 
 type SystemMetrics struct {
-	TimeStamp      time.Time       `json:"timestamp"`
-	CPU            CPUInfo         `json:"cpu"`
-	Memory         MemInfo         `json:"memory"`
-	Disk           DiskInfo        `json:"disk"`
-	System         SystemInfo      `json:"system"`
-	Alerts         []Alert         `json:"alerts,omitempty"`
-	Network        NetworkStats    `json:"network"`
-	Processes      []ProcessInfo   `json:"processes"`
-	NetworkDetails *NetworkDetails `json:"network_details,omitempty"`
+	TimeStamp      time.Time          `json:"timestamp"`
+	CPU            CPUInfo            `json:"cpu"`
+	Memory         MemInfo            `json:"memory"`
+	Disk           DiskInfo           `json:"disk"`
+	Filesystems    []Filesystem       `json:"filesystems,omitempty"`
+	DiskIO         []DiskIO           `json:"disk_io,omitempty"` // block devices
+	System         SystemInfo         `json:"system"`
+	Alerts         []Alert            `json:"alerts,omitempty"`
+	Network        NetworkStats       `json:"network"`
+	Processes      []ProcessInfo      `json:"processes"`
+	NetworkDetails *NetworkDetails    `json:"network_details,omitempty"`
+	Interfaces     []NetworkInterface `json:"interfaces,omitempty"`     // filtered by SYS_PULSE_NET_INCLUDE / SYS_PULSE_NET_EXCLUDE
+	Events         []Event            `json:"events,omitempty"`         // events happened since previous collection
+	ProcessStats   *ProcessStats      `json:"process_stats,omitempty"`  // process churn
+	ProcessGroups  []ProcessGroup     `json:"process_groups,omitempty"` // watched groups from SYS_PULSE_PROCESS_GROUPS
+	Pressure       *Pressure          `json:"pressure,omitempty"`       // linux PSI, absent when kernel has none
+	Cgroups        *CgroupMetrics     `json:"cgroups,omitempty"`        // cgroup v2 controllers, absent without cgroup v2
+	Containers     []Container        `json:"containers,omitempty"`     // docker containers, absent unless SYS_PULSE_DOCKER_SOCKET is set
+	Sockets        *SocketStats       `json:"sockets,omitempty"`        // from /proc/net, absent without procfs
+	Probes         []ProbeResult      `json:"probes,omitempty"`         // latency probes from SYS_PULSE_PROBES
+	HTTPChecks     []HTTPCheckResult  `json:"http_checks,omitempty"`    // endpoint checks from SYS_PULSE_HTTP_CHECKS
+	Sensors        *Sensors           `json:"sensors,omitempty"`        // hwmon, thermal zones and power supplies, absent when there are none
 }
 
 type CPUInfo struct {
-	Usage  float64 `json:"usage"`  // cpu usage in %
-	Cores  int     `json:"cores"`  // amount of cores
-	Load1  float64 `json:"load1"`  // load avg for 1 min
-	Load5  float64 `json:"load5"`  // load avg for 5 mins
-	Load15 float64 `json:"load15"` // load avg for 15 mins
+	Usage         float64    `json:"usage"`          // cpu usage in %
+	Cores         int        `json:"cores"`          // amount of logical cores
+	PhysicalCores int        `json:"physical_cores"` // amount of physical cores
+	ModelName     string     `json:"model_name"`
+	Load1         float64    `json:"load1"`               // load avg for 1 min
+	Load5         float64    `json:"load5"`               // load avg for 5 mins
+	Load15        float64    `json:"load15"`              // load avg for 15 mins
+	Times         *CPUTimes  `json:"times,omitempty"`     // where cpu time went since previous collection
+	PerCore       []CoreInfo `json:"per_core,omitempty"`  // logical cores
+	Container     bool       `json:"container,omitempty"` // usage and cores are of the own cgroup, see SYS_PULSE_CONTAINER_LIMITS
+}
+
+// share of cpu time in each state, in %. Guest time is also counted in user and nice, as the kernel does
+type CPUTimes struct {
+	User      float64 `json:"user"`
+	Nice      float64 `json:"nice"`
+	System    float64 `json:"system"`
+	Idle      float64 `json:"idle"`
+	IOWait    float64 `json:"iowait"`
+	IRQ       float64 `json:"irq"`
+	SoftIRQ   float64 `json:"softirq"`
+	Steal     float64 `json:"steal"`
+	Guest     float64 `json:"guest"`
+	GuestNice float64 `json:"guest_nice"`
+}
+
+type CoreInfo struct {
+	ID        int     `json:"id"`
+	Usage     float64 `json:"usage"`         // in %
+	Frequency float64 `json:"frequency_mhz"` // current frequency, 0 when unknown
 }
 
 type MemInfo struct {
-	Total     uint64  `json:"total"`     // total memory size in bytes
-	Used      uint64  `json:"used"`      // used memory in bytes
-	Available uint64  `json:"available"` // available memory in bytes
-	Usage     float64 `json:"usage"`     // memory usage in %
+	Total             uint64     `json:"total"`     // total memory size in bytes
+	Used              uint64     `json:"used"`      // used memory in bytes
+	Available         uint64     `json:"available"` // available memory in bytes
+	Usage             float64    `json:"usage"`     // memory usage in %
+	Free              uint64     `json:"free"`
+	Buffers           uint64     `json:"buffers"`
+	Cached            uint64     `json:"cached"` // page cache
+	Shared            uint64     `json:"shared"`
+	SlabReclaimable   uint64     `json:"slab_reclaimable"`
+	SlabUnreclaimable uint64     `json:"slab_unreclaimable"`
+	Dirty             uint64     `json:"dirty"`
+	Writeback         uint64     `json:"writeback"`
+	Swap              SwapInfo   `json:"swap"`
+	HugePages         *HugePages `json:"hugepages,omitempty"` // only when huge pages are configured
+	MinorFaultRate    float64    `json:"minor_faults_per_sec"`
+	MajorFaultRate    float64    `json:"major_faults_per_sec"`
+	OOMKills          uint64     `json:"oom_kills"`           // since boot
+	NewOOMKills       uint64     `json:"new_oom_kills"`       // since previous collection
+	Error             string     `json:"error,omitempty"`     // set when memory could not be read, numbers are zero then
+	Container         bool       `json:"container,omitempty"` // numbers are of the own cgroup and its memory.max
+}
+
+type SwapInfo struct {
+	Total   uint64  `json:"total"`
+	Used    uint64  `json:"used"`
+	Free    uint64  `json:"free"`
+	Usage   float64 `json:"usage"`           // in %
+	InRate  float64 `json:"in_per_sec"`      // bytes swapped in per second
+	OutRate float64 `json:"out_per_sec"`     // bytes swapped out per second
+	Error   string  `json:"error,omitempty"` // set when swap could not be read
+}
+
+type HugePages struct {
+	Total    uint64 `json:"total"` // pages
+	Free     uint64 `json:"free"`
+	Reserved uint64 `json:"reserved"`
+	Surplus  uint64 `json:"surplus"`
+	PageSize uint64 `json:"page_size"` // bytes
 }
 
 type DiskInfo struct {
-	Total uint64  `json:"total"` // total disk volume in bytes
-	Used  uint64  `json:"used"`  // used space in bytes
-	Free  uint64  `json:"free"`  // free spaces in bytes
-	Usage float64 `json:"usage"` // disk usage in %
+	Total      uint64  `json:"total"`                // total disk volume in bytes
+	Used       uint64  `json:"used"`                 // used space in bytes
+	Free       uint64  `json:"free"`                 // free spaces in bytes
+	Usage      float64 `json:"usage"`                // disk usage in %
+	Mountpoint string  `json:"mountpoint,omitempty"` // the largest filesystem is reported here
+	Error      string  `json:"error,omitempty"`      // set when no filesystem could be read, numbers are zero then
+}
+
+// io of one block device, rates are computed between two collections
+type DiskIO struct {
+	Device      string   `json:"device"`                // kernel name, e.g. sda1 or dm-0
+	Mountpoints []string `json:"mountpoints,omitempty"` // filesystems on this device
+	ReadRate    float64  `json:"read_bytes_per_sec"`
+	WriteRate   float64  `json:"write_bytes_per_sec"`
+	ReadOps     float64  `json:"read_ops_per_sec"`
+	WriteOps    float64  `json:"write_ops_per_sec"`
+	ReadAwait   float64  `json:"read_await_ms"`  // average time of one read, queue included
+	WriteAwait  float64  `json:"write_await_ms"` // average time of one write, queue included
+	QueueDepth  float64  `json:"queue_depth"`    // average requests in flight
+	Utilization float64  `json:"utilization"`    // % of time device was busy
+	InFlight    uint64   `json:"in_flight"`      // requests in flight now
+	ReadBytes   uint64   `json:"read_bytes"`     // since boot
+	WriteBytes  uint64   `json:"write_bytes"`    // since boot
+}
+
+// one mounted filesystem
+type Filesystem struct {
+	Device      string   `json:"device"`
+	Mountpoint  string   `json:"mountpoint"`
+	Fstype      string   `json:"fstype"`
+	Options     []string `json:"options"`
+	ReadOnly    bool     `json:"read_only"`
+	Total       uint64   `json:"total"` // bytes
+	Used        uint64   `json:"used"`
+	Free        uint64   `json:"free"`  // available to unprivileged users
+	Usage       float64  `json:"usage"` // in %
+	InodesTotal uint64   `json:"inodes_total"`
+	InodesUsed  uint64   `json:"inodes_used"`
+	InodesFree  uint64   `json:"inodes_free"`
+	InodesUsage float64  `json:"inodes_usage"`    // in %
+	Error       string   `json:"error,omitempty"` // set when usage could not be read
 }
 
 type SystemInfo struct {
-	Hostname string `json:"hostname"` // name of userspace
-	OS       string `json:"os"`       // os name
-	Platform string `json:"platform"` // platform name
-	Uptime   uint64 `json:"uptime"`   // time since system started
+	Hostname            string    `json:"hostname"`                       // name of userspace
+	OS                  string    `json:"os"`                             // os name
+	Platform            string    `json:"platform"`                       // platform name
+	Uptime              uint64    `json:"uptime"`                         // seconds since boot
+	BootTime            time.Time `json:"boot_time,omitzero"`             // zero when it can't be read
+	Kernel              string    `json:"kernel,omitempty"`               // kernel release, e.g. 6.8.0-45-generic
+	Distribution        string    `json:"distribution,omitempty"`         // e.g. ubuntu, debian, centos
+	DistributionVersion string    `json:"distribution_version,omitempty"` // e.g. 24.04
+	Virtualization      string    `json:"virtualization,omitempty"`       // hypervisor or container technology, e.g. kvm, docker
+	VirtualizationRole  string    `json:"virtualization_role,omitempty"`  // host or guest
+	Container           string    `json:"container,omitempty"`            // docker, podman, kubernetes or lxc when running in one
+	CPUModel            string    `json:"cpu_model,omitempty"`
+	MachineID           string    `json:"machine_id,omitempty"` // /etc/machine-id, or host id from gopsutil
+	Timezone            string    `json:"timezone"`             // IANA name when known, e.g. Europe/Moscow, or abbreviation
+	UTCOffset           int       `json:"utc_offset"`           // seconds east of UTC
+	Users               int       `json:"users"`                // login sessions
 }
 
 // system alerts
@@ -59,10 +180,21 @@ type Alert struct {
 
 // alert config
 type AlertConfig struct {
-	CPUTreshold  float64 `json:"cpu_treshold"`
-	RAMTreshold  float64 `json:"ram_treshold"`
-	DiskTreshold float64 `json:"disk_treshold"`
-	Enabled      bool    `json:"enabled"`
+	CPUTreshold  float64     `json:"cpu_treshold"`
+	RAMTreshold  float64     `json:"ram_treshold"`
+	DiskTreshold float64     `json:"disk_treshold"`
+	Enabled      bool        `json:"enabled"`
+	Rules        []AlertRule `json:"rules,omitempty"` // not sent rules are kept on update, empty list removes them
+}
+
+// alert rule compares one metric with threshold, e.g. process.count:nginx < 1
+type AlertRule struct {
+	Name      string  `json:"name"`             // used as alert type
+	Metric    string  `json:"metric"`           // metric key, see README for the list
+	Operator  string  `json:"operator"`         // >, >=, <, <=, == or !=
+	Threshold float64 `json:"threshold"`        // value to compare with
+	Window    string  `json:"window,omitempty"` // time window for counters like process.restarts, e.g. 10m
+	Level     string  `json:"level,omitempty"`  // warning by default, or critical
 }
 
 // alert hystory
@@ -77,6 +209,7 @@ type AlertHistory struct {
 
 type ProcessInfo struct {
 	PID           int32   `json:"pid"`
+	PPID          int32   `json:"ppid"` // parent pid
 	Process       string  `json:"process"`
 	CPUPercent    float64 `json:"cpu_percent"`
 	MemoryPercent float64 `json:"memory_percent"`
@@ -86,26 +219,545 @@ type ProcessInfo struct {
 	User          string  `json:"user"`
 	CreateTime    int64   `json:"createtime"`
 	Threads       int     `json:"threads"`
+	Cgroup        string  `json:"cgroup,omitempty"` // cgroup v2 path, or systemd hierarchy path on v1
 }
 
 type NetworkStats struct {
-	IsOnline        bool    `json:"is_online"`        // am i online ?
-	CurrentUpload   float64 `json:"current_upload"`   // net speed in Mb/s
-	CurrentDownload float64 `json:"current_download"` // net speed in Mb/s
-	Ping            float64 `json:"ping"`             // net dealy in milliseconds
+	IsOnline        bool    `json:"is_online"`        // any probe is up, without probes any reported interface is up and has an address
+	CurrentUpload   float64 `json:"current_upload"`   // net speed in Mb/s, sum of reported interfaces
+	CurrentDownload float64 `json:"current_download"` // net speed in Mb/s, sum of reported interfaces
+	Ping            float64 `json:"ping"`             // latency of the first probe that is up in milliseconds, 0 without probes
 	LocalIP         string  `json:"local_ip"`
 }
 
+// one network interface, counters are since the interface was created, rates since previous collection
+type NetworkInterface struct {
+	Name         string   `json:"name"`
+	MACAddress   string   `json:"mac_address"`
+	Addresses    []string `json:"addresses"` // ip addresses with prefix length
+	MTU          int      `json:"mtu"`
+	Speed        int      `json:"speed_mbps"` // link speed, 0 when unknown (virtual interfaces, link down)
+	Duplex       string   `json:"duplex"`     // full, half or unknown
+	OperState    string   `json:"operstate"`  // up, down, dormant, unknown, ...
+	RxBytes      uint64   `json:"rx_bytes"`
+	TxBytes      uint64   `json:"tx_bytes"`
+	RxPackets    uint64   `json:"rx_packets"`
+	TxPackets    uint64   `json:"tx_packets"`
+	RxErrors     uint64   `json:"rx_errors"`
+	TxErrors     uint64   `json:"tx_errors"`
+	RxDropped    uint64   `json:"rx_dropped"`
+	TxDropped    uint64   `json:"tx_dropped"`
+	RxRate       float64  `json:"rx_bytes_per_sec"`
+	TxRate       float64  `json:"tx_bytes_per_sec"`
+	RxPacketRate float64  `json:"rx_packets_per_sec"`
+	TxPacketRate float64  `json:"tx_packets_per_sec"`
+	RxErrorRate  float64  `json:"rx_errors_per_sec"`
+	TxErrorRate  float64  `json:"tx_errors_per_sec"`
+	RxDropRate   float64  `json:"rx_dropped_per_sec"`
+	TxDropRate   float64  `json:"tx_dropped_per_sec"`
+}
+
 type NetworkDetails struct {
 	PublicIP      string `json:"public_ip"`
 	MACAddress    string `json:"mac_address"`
-	TotalUpload   uint64 `json:"total_upload"`            // total value of used traffic in Mb
-	TotalDownload uint64 `json:"total_download"`          // total value of used traffic in Mb
+	TotalUpload   uint64 `json:"total_upload"`            // bytes sent by reported interfaces since accounting started, kept across restarts
+	TotalDownload uint64 `json:"total_download"`          // bytes received, the same way
 	ErrorMessage  string `json:"error_message,omitempty"` // any error ?
 }
 
-type PingStrategy struct {
-	PrinaryServers  []string // reliable servers
-	FallbackServers []string // additional servers if any problem with primaries
-	TimeoutMS       int
+// hardware sensors from sysfs, thresholds are nil when the driver has none
+type Sensors struct {
+	Chips         []SensorChip  `json:"chips,omitempty"`
+	ThermalZones  []ThermalZone `json:"thermal_zones,omitempty"`
+	PowerSupplies []PowerSupply `json:"power_supplies,omitempty"`
+}
+
+// one hwmon device
+type SensorChip struct {
+	ID           string        `json:"id"`     // chip name, with @<device> added when several chips share the name
+	Name         string        `json:"name"`   // driver name, e.g. coretemp, k10temp, nvme
+	Device       string        `json:"device"` // hwmonN directory in /sys/class/hwmon
+	Temperatures []Temperature `json:"temperatures,omitempty"`
+	Fans         []Fan         `json:"fans,omitempty"`
+	Voltages     []Voltage     `json:"voltages,omitempty"`
+	Power        []PowerSensor `json:"power,omitempty"`
+}
+
+type Temperature struct {
+	Label string   `json:"label"` // from tempN_label, or tempN
+	Value float64  `json:"celsius"`
+	Max   *float64 `json:"max,omitempty"`
+	Crit  *float64 `json:"crit,omitempty"`
+}
+
+type Fan struct {
+	Label string   `json:"label"`
+	RPM   float64  `json:"rpm"`
+	Min   *float64 `json:"min,omitempty"`
+}
+
+type Voltage struct {
+	Label string   `json:"label"`
+	Value float64  `json:"volts"`
+	Min   *float64 `json:"min,omitempty"`
+	Max   *float64 `json:"max,omitempty"`
+}
+
+type PowerSensor struct {
+	Label string  `json:"label"`
+	Value float64 `json:"watts"`
+}
+
+type ThermalZone struct {
+	Name        string   `json:"name"` // thermal_zoneN
+	Type        string   `json:"type"` // e.g. x86_pkg_temp, acpitz
+	Temperature float64  `json:"celsius"`
+	Crit        *float64 `json:"crit,omitempty"` // critical trip point
+}
+
+type PowerSupply struct {
+	Name     string   `json:"name"`               // e.g. BAT0, AC
+	Type     string   `json:"type"`               // Battery, Mains, USB, ...
+	Status   string   `json:"status,omitempty"`   // Charging, Discharging, Full, ... for batteries
+	Online   *bool    `json:"online,omitempty"`   // for external supplies
+	Capacity *float64 `json:"capacity,omitempty"` // percent of charge
+	Power    *float64 `json:"watts,omitempty"`    // current draw or charge rate
+	Voltage  *float64 `json:"volts,omitempty"`
+}
+
+// socket summary, rates are per second since previous collection
+type SocketStats struct {
+	TCP                int               `json:"tcp"`        // tcp sockets of both families
+	UDP                int               `json:"udp"`        // udp sockets of both families
+	TCPStates          map[string]int    `json:"tcp_states"` // state -> count, e.g. ESTABLISHED, TIME_WAIT, CLOSE_WAIT
+	Listening          []ListeningSocket `json:"listening"`  // listening tcp and bound unconnected udp sockets
+	RetransmitRate     float64           `json:"tcp_retransmits_per_sec"`
+	RetransmitPercent  float64           `json:"tcp_retransmit_percent"` // retransmitted of sent segments
+	ResetRate          float64           `json:"tcp_resets_per_sec"`     // resets sent
+	EstabResetRate     float64           `json:"tcp_estab_resets_per_sec"`
+	AttemptFailRate    float64           `json:"tcp_attempt_fails_per_sec"`
+	UDPInErrorRate     float64           `json:"udp_in_errors_per_sec"`
+	UDPRcvbufErrorRate float64           `json:"udp_rcvbuf_errors_per_sec"` // datagrams dropped because receive buffer was full
+	UDPNoPortRate      float64           `json:"udp_no_ports_per_sec"`      // datagrams to ports nobody listens on
+}
+
+type ListeningSocket struct {
+	Protocol string `json:"protocol"` // tcp, tcp6, udp, udp6
+	Address  string `json:"address"`
+	Port     uint32 `json:"port"`
+	PID      int32  `json:"pid,omitempty"` // 0 when owner is not visible, e.g. no permission
+	Process  string `json:"process,omitempty"`
+}
+
+type Connection struct {
+	Protocol   string `json:"protocol"`
+	LocalAddr  string `json:"local_addr"`
+	LocalPort  uint32 `json:"local_port"`
+	RemoteAddr string `json:"remote_addr"`
+	RemotePort uint32 `json:"remote_port"`
+	State      string `json:"state"` // tcp state, NONE for udp like in process details
+	UID        uint32 `json:"uid"`
+	TxQueue    uint64 `json:"tx_queue"` // bytes not yet acknowledged, or queued for udp
+	RxQueue    uint64 `json:"rx_queue"` // bytes not yet read by the process
+	PID        int32  `json:"pid,omitempty"`
+	Process    string `json:"process,omitempty"`
+}
+
+type ConnectionList struct {
+	Connections []Connection `json:"connections"`
+	Total       int          `json:"total"` // amount of connections matching filters
+	Timestamp   time.Time    `json:"timestamp"`
+}
+
+// traffic accounting of reported interfaces, days and months are in local time
+type NetworkTraffic struct {
+	Since      time.Time          `json:"since"` // when accounting started
+	Interfaces []InterfaceTraffic `json:"interfaces"`
+}
+
+type InterfaceTraffic struct {
+	Name    string          `json:"name"`
+	RxBytes uint64          `json:"rx_bytes"` // total since accounting started
+	TxBytes uint64          `json:"tx_bytes"`
+	Daily   []TrafficPeriod `json:"daily"`   // newest first
+	Monthly []TrafficPeriod `json:"monthly"` // newest first
+}
+
+type TrafficPeriod struct {
+	Period  string `json:"period"` // 2006-01-02 for days, 2006-01 for months
+	RxBytes uint64 `json:"rx_bytes"`
+	TxBytes uint64 `json:"tx_bytes"`
+}
+
+// latency probe definition from SYS_PULSE_PROBES
+type ProbeConfig struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`               // tcp, icmp or dns
+	Target   string `json:"target"`             // host:port for tcp, host for icmp, server[:port] for dns
+	Query    string `json:"query,omitempty"`    // name asked in dns probes, the root zone by default
+	Interval string `json:"interval,omitempty"` // 10s by default
+	Timeout  string `json:"timeout,omitempty"`  // 2s by default
+	Window   int    `json:"window,omitempty"`   // attempts used for loss and jitter, 20 by default
+}
+
+// http check definition from SYS_PULSE_HTTP_CHECKS
+type HTTPCheckConfig struct {
+	Name               string            `json:"name"`
+	URL                string            `json:"url"`
+	Method             string            `json:"method,omitempty"`          // GET by default
+	Headers            map[string]string `json:"headers,omitempty"`         // Host is used as request host
+	ExpectedStatus     []int             `json:"expected_status,omitempty"` // any 2xx or 3xx by default, redirects are not followed
+	BodyRegex          string            `json:"body_regex,omitempty"`      // regular expression the body must match
+	Interval           string            `json:"interval,omitempty"`        // 30s by default
+	Timeout            string            `json:"timeout,omitempty"`         // 5s by default
+	InsecureSkipVerify bool              `json:"insecure_skip_verify,omitempty"`
+}
+
+// result of the last run of an http check, times are in milliseconds
+type HTTPCheckResult struct {
+	Name           string     `json:"name"`
+	URL            string     `json:"url"`
+	Status         string     `json:"status"` // up, down, or unknown before the first run
+	StatusCode     int        `json:"status_code,omitempty"`
+	ResponseTime   float64    `json:"response_time_ms"` // until the body was read
+	DNS            float64    `json:"dns_ms"`
+	Connect        float64    `json:"connect_ms"`
+	TLS            float64    `json:"tls_ms"`
+	TTFB           float64    `json:"ttfb_ms"`                    // from start to first response byte, dns, connect and tls included
+	CertExpiry     *time.Time `json:"cert_expiry,omitempty"`      // of the server certificate, https only
+	CertExpiryDays *float64   `json:"cert_expiry_days,omitempty"` // negative when expired
+	LastError      string     `json:"last_error,omitempty"`
+	LastCheck      time.Time  `json:"last_check,omitempty"`
+}
+
+// probe state over its window
+type ProbeResult struct {
+	Name       string    `json:"name"`
+	Type       string    `json:"type"`
+	Target     string    `json:"target"`
+	Status     string    `json:"status"`     // up, down, or unknown before the first attempt
+	Latency    float64   `json:"latency_ms"` // last successful attempt
+	AvgLatency float64   `json:"avg_latency_ms"`
+	Jitter     float64   `json:"jitter_ms"`    // mean difference of consecutive latencies
+	Loss       float64   `json:"loss_percent"` // failed attempts in window
+	Attempts   int       `json:"attempts"`     // attempts in window
+	LastError  string    `json:"last_error,omitempty"`
+	LastCheck  time.Time `json:"last_check,omitempty"`
+}
+
+// one page of /api/processes
+type ProcessList struct {
+	Processes  []ProcessInfo `json:"processes"`
+	Total      int           `json:"total"`                 // amount of processes matching filters
+	NextCursor string        `json:"next_cursor,omitempty"` // pass as cursor to get next page, empty on last page
+}
+
+// process tree, built from PPID of processes
+type ProcessTree struct {
+	GroupBy   string         `json:"group_by,omitempty"` // empty for plain tree, name, unit or cgroup
+	Roots     []*ProcessNode `json:"roots"`
+	Total     ProcessTotals  `json:"total"`
+	Timestamp time.Time      `json:"timestamp"`
+}
+
+type ProcessNode struct {
+	Name     string         `json:"name"`              // process name, unit or cgroup of group
+	Process  *ProcessInfo   `json:"process,omitempty"` // nil for unit and cgroup groups
+	Merged   []int32        `json:"merged,omitempty"`  // pids of same-named children folded into this node
+	Total    ProcessTotals  `json:"total"`             // usage of the whole subtree
+	Children []*ProcessNode `json:"children,omitempty"`
+}
+
+type ProcessTotals struct {
+	Processes     int     `json:"processes"`
+	Threads       int     `json:"threads"`
+	CPUPercent    float64 `json:"cpu_percent"`
+	MemoryRSS     uint64  `json:"memory_rss"`
+	MemoryPercent float64 `json:"memory_percent"`
+}
+
+// on-demand details of one process, parts that can't be read are listed in errors
+type ProcessDetails struct {
+	Process     ProcessInfo        `json:"process"`
+	Exe         string             `json:"exe,omitempty"`
+	Cwd         string             `json:"cwd,omitempty"`
+	Cgroup      string             `json:"cgroup,omitempty"`
+	IO          *ProcessIO         `json:"io,omitempty"`
+	FDs         *ProcessFDs        `json:"fds,omitempty"`
+	Sockets     *ProcessSockets    `json:"sockets,omitempty"`
+	MemoryMaps  []MemoryMapSummary `json:"memory_maps,omitempty"`
+	Environment []string           `json:"environment,omitempty"` // only for admin
+	Errors      map[string]string  `json:"errors,omitempty"`      // part -> error, e.g. io -> permission denied
+	Timestamp   time.Time          `json:"timestamp"`
+}
+
+type ProcessIO struct {
+	ReadBytes  uint64   `json:"read_bytes"`           // bytes read from storage
+	WriteBytes uint64   `json:"write_bytes"`          // bytes written to storage
+	ReadChars  uint64   `json:"read_chars"`           // bytes passed to read syscalls, cache hits included
+	WriteChars uint64   `json:"write_chars"`          // bytes passed to write syscalls
+	ReadOps    uint64   `json:"read_ops"`             // read syscalls
+	WriteOps   uint64   `json:"write_ops"`            // write syscalls
+	ReadRate   *float64 `json:"read_rate,omitempty"`  // storage read bytes/s, absent on first request for the process
+	WriteRate  *float64 `json:"write_rate,omitempty"` // storage write bytes/s
+}
+
+type ProcessFDs struct {
+	Count     int         `json:"count"`
+	Limit     uint64      `json:"limit"`     // soft limit of open files, 0 if unlimited or unknown
+	Files     []ProcessFD `json:"files"`     // first fd_limit descriptors
+	Truncated bool        `json:"truncated"` // there are more descriptors than listed
+}
+
+type ProcessFD struct {
+	FD     int    `json:"fd"`
+	Target string `json:"target"` // file path, socket:[inode], pipe:[inode], etc.
+}
+
+type ProcessSockets struct {
+	Listening   []ProcessSocket `json:"listening"`
+	Established []ProcessSocket `json:"established"`
+	Other       int             `json:"other"` // sockets in other states
+}
+
+type ProcessSocket struct {
+	Protocol   string `json:"protocol"` // tcp, tcp6, udp, udp6
+	LocalAddr  string `json:"local_addr"`
+	LocalPort  uint32 `json:"local_port"`
+	RemoteAddr string `json:"remote_addr,omitempty"`
+	RemotePort uint32 `json:"remote_port,omitempty"`
+	Status     string `json:"status"`
+}
+
+// memory of process summed by mapping type
+type MemoryMapSummary struct {
+	Type     string `json:"type"` // heap, stack, anon, file, shmem or kernel
+	Mappings int    `json:"mappings"`
+	Size     uint64 `json:"size"` // bytes of address space
+	RSS      uint64 `json:"rss"`
+	PSS      uint64 `json:"pss"` // proportional set size, shared pages divided among processes
+	Swap     uint64 `json:"swap"`
+}
+
+// event types
+const (
+	EventProcessStarted = "process_started"
+	EventProcessExited  = "process_exited"
+	EventGroupRestarted = "group_restarted"
+
+	EventContainerCreated   = "container_created"
+	EventContainerStarted   = "container_started"
+	EventContainerExited    = "container_exited"
+	EventContainerOOM       = "container_oom"
+	EventContainerPaused    = "container_paused"
+	EventContainerUnpaused  = "container_unpaused"
+	EventContainerHealth    = "container_health_changed"
+	EventContainerDestroyed = "container_destroyed"
+)
+
+// something that happened on the host, e.g. process started or exited
+type Event struct {
+	ID        string              `json:"id"`
+	Type      string              `json:"type"` // process_started, process_exited, group_restarted, container_*
+	Timestamp time.Time           `json:"timestamp"`
+	Message   string              `json:"message"`
+	Process   *ProcessEventInfo   `json:"process,omitempty"`
+	Group     string              `json:"group,omitempty"` // process group of group events
+	Container *ContainerEventInfo `json:"container,omitempty"`
+}
+
+type ContainerEventInfo struct {
+	ID       string `json:"id"` // short id
+	Name     string `json:"name"`
+	Image    string `json:"image"`
+	ExitCode *int   `json:"exit_code,omitempty"` // for container_exited
+	Health   string `json:"health,omitempty"`    // for container_health_changed
+}
+
+type ProcessEventInfo struct {
+	PID         int32   `json:"pid"`
+	PPID        int32   `json:"ppid"`
+	Name        string  `json:"name"`
+	CommandLine string  `json:"commandline"`
+	User        string  `json:"user"`
+	Cgroup      string  `json:"cgroup,omitempty"`
+	CreateTime  int64   `json:"createtime"`
+	Lifetime    float64 `json:"lifetime,omitempty"`    // seconds, only for exited processes
+	CPUPercent  float64 `json:"cpu_percent,omitempty"` // last seen usage, only for exited processes
+	MemoryRSS   uint64  `json:"memory_rss,omitempty"`  // last seen usage, only for exited processes
+}
+
+// process churn between two collections
+type ProcessStats struct {
+	Running         int    `json:"running"`           // processes seen in this collection
+	Started         int    `json:"started"`           // new processes since previous collection
+	Exited          int    `json:"exited"`            // processes gone since previous collection
+	ShortLived      uint64 `json:"short_lived"`       // estimate of processes that started and exited between collections
+	TotalStarted    uint64 `json:"total_started"`     // since SysPulse start
+	TotalExited     uint64 `json:"total_exited"`      // since SysPulse start
+	TotalShortLived uint64 `json:"total_short_lived"` // since SysPulse start
+}
+
+// page of /api/events
+type EventList struct {
+	Events []Event `json:"events"`
+	Total  int     `json:"total"` // events kept in history
+}
+
+// process group definition, every set field must match. Process and Cmdline are regular expressions,
+// Cgroup is a path prefix
+type ProcessGroupConfig struct {
+	Name    string `json:"name"`
+	Process string `json:"process,omitempty"`
+	User    string `json:"user,omitempty"`
+	Cmdline string `json:"cmdline,omitempty"`
+	Cgroup  string `json:"cgroup,omitempty"`
+}
+
+// aggregated usage of processes in a group
+type ProcessGroup struct {
+	Name          string  `json:"name"`
+	Status        string  `json:"status"` // up when group has at least one process, down otherwise
+	Processes     int     `json:"processes"`
+	Threads       int     `json:"threads"`
+	CPUPercent    float64 `json:"cpu_percent"`
+	MemoryRSS     uint64  `json:"memory_rss"`
+	MemoryPercent float64 `json:"memory_percent"`
+	Uptime        float64 `json:"uptime"`   // seconds since the oldest process of group started
+	Restarts      int     `json:"restarts"` // since SysPulse start, a restart is a start after an exit
+	PIDs          []int32 `json:"pids"`
+}
+
+// container from the docker engine api, Usage is joined from its cgroup while it runs
+type Container struct {
+	ID           string            `json:"id"` // short id
+	Name         string            `json:"name"`
+	Image        string            `json:"image"`
+	State        string            `json:"state"`            // created, running, paused, restarting, exited, dead
+	Status       string            `json:"status"`           // as docker ps shows it, e.g. "Up 2 hours (healthy)"
+	Health       string            `json:"health,omitempty"` // starting, healthy or unhealthy, only with a healthcheck
+	RestartCount int               `json:"restart_count"`
+	ExitCode     int               `json:"exit_code"`
+	OOMKilled    bool              `json:"oom_killed,omitempty"`
+	Created      time.Time         `json:"created"`
+	StartedAt    time.Time         `json:"started_at,omitzero"`
+	Labels       map[string]string `json:"labels,omitempty"`
+	PID          int32             `json:"pid,omitempty"`
+	Cgroup       string            `json:"cgroup,omitempty"`
+	Usage        *ContainerUsage   `json:"usage,omitempty"`
+}
+
+// rates are since previous collection, net is absent for containers in host network
+type ContainerUsage struct {
+	CPUPercent     float64           `json:"cpu_percent"`  // 100 is one core
+	CPULimit       float64           `json:"cpu_limit"`    // cores, 0 without limit
+	MemoryUsage    uint64            `json:"memory_usage"` // working set, as docker stats shows it
+	MemoryLimit    uint64            `json:"memory_limit,omitempty"`
+	MemoryPercent  float64           `json:"memory_percent"` // of the limit, 0 without limit
+	PIDs           uint64            `json:"pids"`
+	Network        *ContainerNetwork `json:"network,omitempty"`
+	BlockRead      uint64            `json:"block_read_bytes"`
+	BlockWrite     uint64            `json:"block_write_bytes"`
+	BlockReadRate  float64           `json:"block_read_bytes_per_sec"`
+	BlockWriteRate float64           `json:"block_write_bytes_per_sec"`
+}
+
+// summed over interfaces of the container's network namespace, loopback excluded
+type ContainerNetwork struct {
+	RxBytes uint64  `json:"rx_bytes"`
+	TxBytes uint64  `json:"tx_bytes"`
+	RxRate  float64 `json:"rx_bytes_per_sec"`
+	TxRate  float64 `json:"tx_bytes_per_sec"`
+}
+
+// cgroup v2 stats of the cgroup syspulse runs in and of cgroups chosen with SYS_PULSE_CGROUPS
+type CgroupMetrics struct {
+	Self    *CgroupStats  `json:"self,omitempty"`
+	Cgroups []CgroupStats `json:"cgroups,omitempty"`
+}
+
+// controllers that are not enabled for a cgroup are nil or zero
+type CgroupStats struct {
+	Path             string        `json:"path"`              // relative to cgroup root, e.g. /system.slice/nginx.service
+	CPUPercent       float64       `json:"cpu_percent"`       // since previous collection, 100 is one core
+	CPULimit         float64       `json:"cpu_limit"`         // cores allowed by cpu.max, 0 without limit
+	CPUs             int           `json:"cpus,omitempty"`    // cpus in cpuset.cpus.effective
+	CPUUsage         uint64        `json:"cpu_usage_usec"`    // total cpu time
+	ThrottledPeriods uint64        `json:"nr_throttled"`      // total periods the quota was used up
+	ThrottledTime    uint64        `json:"throttled_usec"`    // total time tasks waited for next period
+	ThrottledPercent float64       `json:"throttled_percent"` // of enforcement periods since previous collection
+	Memory           *CgroupMemory `json:"memory,omitempty"`
+	IO               []CgroupIO    `json:"io,omitempty"`
+	PIDs             *CgroupPIDs   `json:"pids,omitempty"`
+}
+
+// memory in bytes, limits are nil when set to max
+type CgroupMemory struct {
+	Current           uint64             `json:"current"`
+	WorkingSet        uint64             `json:"working_set"` // current without inactive page cache, what counts toward oom
+	Max               *uint64            `json:"max,omitempty"`
+	High              *uint64            `json:"high,omitempty"`
+	Usage             float64            `json:"usage"` // working set in % of max, 0 without limit
+	Anon              uint64             `json:"anon"`
+	File              uint64             `json:"file"` // page cache
+	Shmem             uint64             `json:"shmem"`
+	SlabReclaimable   uint64             `json:"slab_reclaimable"`
+	SlabUnreclaimable uint64             `json:"slab_unreclaimable"`
+	Dirty             uint64             `json:"dirty"`
+	Writeback         uint64             `json:"writeback"`
+	SwapCurrent       uint64             `json:"swap_current"`
+	SwapMax           *uint64            `json:"swap_max,omitempty"`
+	Events            CgroupMemoryEvents `json:"events"`
+	NewOOMKills       uint64             `json:"new_oom_kills"` // since previous collection
+}
+
+// memory.events counters, totals since the cgroup was created
+type CgroupMemoryEvents struct {
+	Low     uint64 `json:"low"`
+	High    uint64 `json:"high"` // throttled over memory.high
+	Max     uint64 `json:"max"`  // usage reached memory.max
+	OOM     uint64 `json:"oom"`
+	OOMKill uint64 `json:"oom_kill"`
+}
+
+type CgroupIO struct {
+	Device     string  `json:"device"` // block device name, or major:minor when unknown
+	ReadBytes  uint64  `json:"read_bytes"`
+	WriteBytes uint64  `json:"write_bytes"`
+	ReadOps    uint64  `json:"read_ops"`
+	WriteOps   uint64  `json:"write_ops"`
+	ReadRate   float64 `json:"read_bytes_per_sec"`
+	WriteRate  float64 `json:"write_bytes_per_sec"`
+}
+
+type CgroupPIDs struct {
+	Current uint64  `json:"current"`
+	Max     *uint64 `json:"max,omitempty"`
+}
+
+// Pressure Stall Information: share of time tasks were stalled waiting for a resource
+type Pressure struct {
+	CPU     *PressureResource `json:"cpu,omitempty"`
+	Memory  *PressureResource `json:"memory,omitempty"`
+	IO      *PressureResource `json:"io,omitempty"`
+	Cgroups []CgroupPressure  `json:"cgroups,omitempty"`
+}
+
+type PressureResource struct {
+	Some PressureStat  `json:"some"`           // at least one task stalled
+	Full *PressureStat `json:"full,omitempty"` // all non-idle tasks stalled, not reported for cpu by older kernels
+}
+
+type PressureStat struct {
+	Avg10  float64 `json:"avg10"`  // % of last 10 seconds
+	Avg60  float64 `json:"avg60"`  // % of last 60 seconds
+	Avg300 float64 `json:"avg300"` // % of last 300 seconds
+	Total  uint64  `json:"total"`  // total stall time in microseconds
+}
+
+type CgroupPressure struct {
+	Path   string            `json:"path"` // cgroup path, e.g. /system.slice
+	CPU    *PressureResource `json:"cpu,omitempty"`
+	Memory *PressureResource `json:"memory,omitempty"`
+	IO     *PressureResource `json:"io,omitempty"`
 }
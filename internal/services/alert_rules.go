@@ -0,0 +1,607 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"syspulse/internal/models"
+	"time"
+)
+
+var ErrInvalidAlertRule = errors.New("invalid alert rule")
+
+// maxRuleWindow limits how long event timestamps are kept for windowed rules
+const maxRuleWindow = 24 * time.Hour
+
+var ruleOperators = map[string]func(value, threshold float64) bool{
+	">":  func(v, t float64) bool { return v > t },
+	">=": func(v, t float64) bool { return v >= t },
+	"<":  func(v, t float64) bool { return v < t },
+	"<=": func(v, t float64) bool { return v <= t },
+	"==": func(v, t float64) bool { return v == t },
+	"!=": func(v, t float64) bool { return v != t },
+}
+
+type ruleMetric struct {
+	needsArg    bool // metric is about one named thing, e.g. process.count:nginx
+	needsWindow bool // metric counts events in the rule window
+}
+
+// metrics that rules can use. Keys with ":" take an argument, e.g. process.count:nginx
+var ruleMetrics = map[string]ruleMetric{
+	"cpu.usage":              {},
+	"memory.usage":           {},
+	"memory.oom_kills":       {}, // oom kills since previous collection
+	"memory.major_faults":    {}, // major page faults per second
+	"swap.usage":             {},
+	"swap.out_rate":          {},               // bytes swapped out per second
+	"disk.usage":             {},               // the largest filesystem
+	"fs.usage":               {needsArg: true}, // filesystem with this mount point
+	"fs.inodes_usage":        {needsArg: true},
+	"fs.read_only":           {needsArg: true}, // 1 when mounted read-only
+	"diskio.utilization":     {needsArg: true}, // block device by kernel name or mount point
+	"diskio.read_await":      {needsArg: true},
+	"diskio.write_await":     {needsArg: true},
+	"diskio.queue_depth":     {needsArg: true},
+	"diskio.read_rate":       {needsArg: true},
+	"diskio.write_rate":      {needsArg: true},
+	"net.up":                 {needsArg: true}, // 1 when interface with this name is up
+	"net.rx_rate":            {needsArg: true}, // bytes per second
+	"net.tx_rate":            {needsArg: true},
+	"net.rx_errors":          {needsArg: true}, // errors per second
+	"net.tx_errors":          {needsArg: true},
+	"net.rx_dropped":         {needsArg: true}, // dropped packets per second
+	"net.tx_dropped":         {needsArg: true},
+	"tcp.state":              {needsArg: true}, // tcp sockets in this state, e.g. tcp.state:CLOSE_WAIT
+	"tcp.retransmits":        {},               // retransmitted segments per second
+	"tcp.retransmit_percent": {},
+	"tcp.resets":             {},               // resets sent per second
+	"udp.errors":             {},               // udp receive errors per second
+	"port.listening":         {needsArg: true}, // 1 when something listens on this tcp or udp port
+	"probe.up":               {needsArg: true}, // 1 when probe with this name is up
+	"probe.latency":          {needsArg: true}, // milliseconds
+	"probe.jitter":           {needsArg: true},
+	"probe.loss":             {needsArg: true}, // percent of failed attempts in window
+	"http.up":                {needsArg: true}, // 1 when http check with this name is up
+	"http.response_time":     {needsArg: true}, // milliseconds
+	"http.status_code":       {needsArg: true},
+	"http.cert_expiry_days":  {needsArg: true}, // days until certificate expires, https only
+	"sensor.temperature":     {needsArg: true}, // °C of <chip>/<label>, e.g. coretemp/Package id 0, or of a thermal zone
+	"sensor.fan":             {needsArg: true}, // rpm of <chip>/<label>
+	"sensor.voltage":         {needsArg: true}, // volts of <chip>/<label>
+	"battery.capacity":       {needsArg: true}, // percent of charge of power supply with this name, e.g. BAT0
+	"power.online":           {needsArg: true}, // 1 when external power supply with this name is connected, e.g. AC
+	"cgroup.cpu_percent":     {needsArg: true}, // cgroup with this path or "self", 100 is one core
+	"cgroup.throttling":      {needsArg: true}, // cpu periods throttled by cpu.max
+	"cgroup.memory_usage":    {needsArg: true}, // working set in % of memory.max
+	"cgroup.memory_current":  {needsArg: true}, // bytes
+	"cgroup.oom_kills":       {needsArg: true}, // oom kills since previous collection
+	"cgroup.pids":            {needsArg: true},
+	"processes.running":      {},
+	"processes.short_lived":  {},
+	"process.count":          {needsArg: true},                    // running processes with this name
+	"process.restarts":       {needsArg: true, needsWindow: true}, // starts of processes with this name in window
+	"group.processes":        {needsArg: true},                    // the rest are about process group with this name
+	"group.cpu_percent":      {needsArg: true},
+	"group.memory_rss":       {needsArg: true},
+	"group.memory_percent":   {needsArg: true},
+	"group.uptime":           {needsArg: true},
+	"group.restarts":         {needsArg: true, needsWindow: true},
+}
+
+func init() {
+	// psi.<resource>.<some|full>.<avg10|avg60|avg300> and the same for a cgroup, e.g. cgroup.psi.io.full.avg60:/system.slice
+	for _, resource := range pressureResources {
+		for _, kind := range []string{"some", "full"} {
+			for _, avg := range []string{"avg10", "avg60", "avg300"} {
+				key := fmt.Sprintf("psi.%s.%s.%s", resource, kind, avg)
+				ruleMetrics[key] = ruleMetric{}
+				ruleMetrics["cgroup."+key] = ruleMetric{needsArg: true}
+			}
+		}
+	}
+}
+
+// ParseAlertRules reads json list of rules, as given in SYS_PULSE_ALERT_RULES
+func ParseAlertRules(data string) ([]models.AlertRule, error) {
+	if strings.TrimSpace(data) == "" {
+		return nil, nil
+	}
+
+	var rules []models.AlertRule
+	if err := json.Unmarshal([]byte(data), &rules); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAlertRule, err)
+	}
+	if err := ValidateAlertRules(rules); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+func ValidateAlertRules(rules []models.AlertRule) error {
+	names := make(map[string]bool)
+	for _, rule := range rules {
+		if err := validateAlertRule(rule); err != nil {
+			return err
+		}
+		if names[rule.Name] {
+			return fmt.Errorf("%w: duplicate name %q", ErrInvalidAlertRule, rule.Name)
+		}
+		names[rule.Name] = true
+	}
+	return nil
+}
+
+func validateAlertRule(rule models.AlertRule) error {
+	if rule.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidAlertRule)
+	}
+	if _, ok := ruleOperators[rule.Operator]; !ok {
+		return fmt.Errorf("%w: rule %q: operator must be one of >, >=, <, <=, ==, !=", ErrInvalidAlertRule, rule.Name)
+	}
+	if rule.Level != "" && rule.Level != "warning" && rule.Level != "critical" {
+		return fmt.Errorf("%w: rule %q: level must be warning or critical", ErrInvalidAlertRule, rule.Name)
+	}
+
+	key, arg, hasArg := strings.Cut(rule.Metric, ":")
+	metric, ok := ruleMetrics[key]
+	if !ok {
+		return fmt.Errorf("%w: rule %q: unknown metric %q", ErrInvalidAlertRule, rule.Name, rule.Metric)
+	}
+	if metric.needsArg != (hasArg && arg != "") {
+		if metric.needsArg {
+			return fmt.Errorf("%w: rule %q: metric %s needs a name, e.g. %s:nginx", ErrInvalidAlertRule, rule.Name, key, key)
+		}
+		return fmt.Errorf("%w: rule %q: metric %s takes no name", ErrInvalidAlertRule, rule.Name, key)
+	}
+
+	if metric.needsWindow || rule.Window != "" {
+		window, err := time.ParseDuration(rule.Window)
+		if err != nil || window <= 0 || window > maxRuleWindow {
+			return fmt.Errorf("%w: rule %q: window must be a duration up to 24h, e.g. 10m", ErrInvalidAlertRule, rule.Name)
+		}
+	}
+	return nil
+}
+
+// ruleState is what rules need to remember between collections
+type ruleState struct {
+	starts        map[string][]time.Time // process name -> start times, only for names used in restart rules
+	groupRestarts map[string][]time.Time // group name -> restart times
+}
+
+// checkRules evaluates custom rules. Unlike threshold alerts a rule alert is created once when condition
+// becomes true and is resolved when it becomes false
+func (as *AlertService) checkRules(metrics models.SystemMetrics, now time.Time) []models.Alert {
+	rules := as.GetConfig().Rules
+	as.recordEvents(rules, metrics.Events, now)
+
+	var newAlerts []models.Alert
+	for _, rule := range rules {
+		alertType := "RULE:" + rule.Name
+		value, ok := as.ruleValue(rule, metrics, now)
+		if !ok || !ruleOperators[rule.Operator](value, rule.Threshold) {
+			as.resolveAlert(alertType)
+			continue
+		}
+		if as.activeAlerts[alertType] {
+			continue
+		}
+
+		level := rule.Level
+		if level == "" {
+			level = "warning"
+		}
+		as.activeAlerts[alertType] = true
+		newAlerts = append(newAlerts, models.Alert{
+			ID:        generateID(alertType, now),
+			Type:      alertType,
+			Message:   fmt.Sprintf("[%s] %s: %s = %g (%s %g)\n", rule.Name, level, rule.Metric, value, rule.Operator, rule.Threshold),
+			Level:     level,
+			Threshold: rule.Threshold,
+			Timestamp: now,
+			Active:    true,
+		})
+	}
+	return newAlerts
+}
+
+func (as *AlertService) ruleValue(rule models.AlertRule, metrics models.SystemMetrics, now time.Time) (float64, bool) {
+	key, arg, _ := strings.Cut(rule.Metric, ":")
+	switch key {
+	case "cpu.usage":
+		return metrics.CPU.Usage, true
+	case "memory.usage":
+		return metrics.Memory.Usage, metrics.Memory.Error == ""
+	case "memory.oom_kills":
+		return float64(metrics.Memory.NewOOMKills), metrics.Memory.Error == ""
+	case "memory.major_faults":
+		return metrics.Memory.MajorFaultRate, metrics.Memory.Error == ""
+	case "swap.usage":
+		return metrics.Memory.Swap.Usage, metrics.Memory.Error == "" && metrics.Memory.Swap.Error == ""
+	case "swap.out_rate":
+		return metrics.Memory.Swap.OutRate, metrics.Memory.Error == ""
+	case "disk.usage":
+		return metrics.Disk.Usage, metrics.Disk.Error == ""
+	case "fs.usage", "fs.inodes_usage", "fs.read_only":
+		for _, fs := range metrics.Filesystems {
+			if fs.Mountpoint != arg {
+				continue
+			}
+			switch key {
+			case "fs.usage":
+				return fs.Usage, fs.Error == ""
+			case "fs.inodes_usage":
+				return fs.InodesUsage, fs.Error == ""
+			default:
+				if fs.ReadOnly {
+					return 1, true
+				}
+				return 0, true
+			}
+		}
+	case "tcp.state", "tcp.retransmits", "tcp.retransmit_percent", "tcp.resets", "udp.errors", "port.listening":
+		return socketValue(metrics.Sockets, key, arg)
+	case "probe.up", "probe.latency", "probe.jitter", "probe.loss":
+		for _, probe := range metrics.Probes {
+			if probe.Name != arg || probe.Status == "unknown" {
+				continue
+			}
+			switch key {
+			case "probe.up":
+				if probe.Status == "up" {
+					return 1, true
+				}
+				return 0, true
+			case "probe.latency":
+				return probe.Latency, probe.Status == "up"
+			case "probe.jitter":
+				return probe.Jitter, true
+			default:
+				return probe.Loss, true
+			}
+		}
+	case "http.up", "http.response_time", "http.status_code", "http.cert_expiry_days":
+		for _, check := range metrics.HTTPChecks {
+			if check.Name != arg || check.Status == "unknown" {
+				continue
+			}
+			switch key {
+			case "http.up":
+				if check.Status == "up" {
+					return 1, true
+				}
+				return 0, true
+			case "http.response_time":
+				return check.ResponseTime, true
+			case "http.status_code":
+				return float64(check.StatusCode), check.StatusCode != 0
+			default:
+				if check.CertExpiryDays == nil {
+					return 0, false
+				}
+				return *check.CertExpiryDays, true
+			}
+		}
+	case "sensor.temperature", "sensor.fan", "sensor.voltage", "battery.capacity", "power.online":
+		return sensorValue(metrics.Sensors, key, arg)
+	case "cgroup.cpu_percent", "cgroup.throttling", "cgroup.memory_usage", "cgroup.memory_current", "cgroup.oom_kills", "cgroup.pids":
+		return cgroupValue(metrics.Cgroups, key, arg)
+	case "processes.running":
+		return float64(len(metrics.Processes)), true
+	case "processes.short_lived":
+		if metrics.ProcessStats == nil {
+			return 0, false
+		}
+		return float64(metrics.ProcessStats.ShortLived), true
+	case "process.count":
+		count := 0
+		for _, p := range metrics.Processes {
+			if p.Process == arg {
+				count++
+			}
+		}
+		return float64(count), true
+	case "process.restarts":
+		return as.countInWindow(as.rules.starts[arg], rule.Window, now), true
+	case "group.restarts":
+		return as.countInWindow(as.rules.groupRestarts[arg], rule.Window, now), true
+	case "group.processes", "group.cpu_percent", "group.memory_rss", "group.memory_percent", "group.uptime":
+		for _, group := range metrics.ProcessGroups {
+			if group.Name != arg {
+				continue
+			}
+			switch key {
+			case "group.processes":
+				return float64(group.Processes), true
+			case "group.cpu_percent":
+				return group.CPUPercent, true
+			case "group.memory_rss":
+				return float64(group.MemoryRSS), true
+			case "group.memory_percent":
+				return group.MemoryPercent, true
+			default:
+				return group.Uptime, true
+			}
+		}
+	}
+
+	if field, ok := strings.CutPrefix(key, "diskio."); ok {
+		return diskIOValue(metrics.DiskIO, arg, field)
+	}
+	if field, ok := strings.CutPrefix(key, "net."); ok {
+		return interfaceValue(metrics.Interfaces, arg, field)
+	}
+	if psi, ok := strings.CutPrefix(key, "psi."); ok && metrics.Pressure != nil {
+		return resourcePressure(psi, metrics.Pressure.CPU, metrics.Pressure.Memory, metrics.Pressure.IO)
+	}
+	if psi, ok := strings.CutPrefix(key, "cgroup.psi."); ok && metrics.Pressure != nil {
+		for _, cgroup := range metrics.Pressure.Cgroups {
+			if cgroup.Path == arg {
+				return resourcePressure(psi, cgroup.CPU, cgroup.Memory, cgroup.IO)
+			}
+		}
+	}
+	return 0, false
+}
+
+func diskIOValue(devices []models.DiskIO, name, field string) (float64, bool) {
+	for _, io := range devices {
+		if io.Device != name && !slices.Contains(io.Mountpoints, name) {
+			continue
+		}
+		switch field {
+		case "utilization":
+			return io.Utilization, true
+		case "read_await":
+			return io.ReadAwait, true
+		case "write_await":
+			return io.WriteAwait, true
+		case "queue_depth":
+			return io.QueueDepth, true
+		case "read_rate":
+			return io.ReadRate, true
+		case "write_rate":
+			return io.WriteRate, true
+		}
+	}
+	return 0, false
+}
+
+func socketValue(sockets *models.SocketStats, key, arg string) (float64, bool) {
+	if sockets == nil {
+		return 0, false
+	}
+	switch key {
+	case "tcp.state":
+		return float64(sockets.TCPStates[strings.ToUpper(arg)]), true
+	case "tcp.retransmits":
+		return sockets.RetransmitRate, true
+	case "tcp.retransmit_percent":
+		return sockets.RetransmitPercent, true
+	case "tcp.resets":
+		return sockets.ResetRate, true
+	case "udp.errors":
+		return sockets.UDPInErrorRate, true
+	default:
+		for _, listening := range sockets.Listening {
+			if strconv.FormatUint(uint64(listening.Port), 10) == arg {
+				return 1, true
+			}
+		}
+		return 0, true
+	}
+}
+
+func sensorValue(sensors *models.Sensors, key, arg string) (float64, bool) {
+	if sensors == nil {
+		return 0, false
+	}
+	switch key {
+	case "battery.capacity", "power.online":
+		for _, supply := range sensors.PowerSupplies {
+			if supply.Name != arg {
+				continue
+			}
+			if key == "battery.capacity" {
+				if supply.Capacity == nil {
+					return 0, false
+				}
+				return *supply.Capacity, true
+			}
+			if supply.Online == nil {
+				return 0, false
+			}
+			if *supply.Online {
+				return 1, true
+			}
+			return 0, true
+		}
+		return 0, false
+	}
+
+	chipID, label, _ := strings.Cut(arg, "/")
+	for _, chip := range sensors.Chips {
+		if chip.ID != chipID {
+			continue
+		}
+		switch key {
+		case "sensor.temperature":
+			for _, temperature := range chip.Temperatures {
+				if temperature.Label == label {
+					return temperature.Value, true
+				}
+			}
+		case "sensor.fan":
+			for _, fan := range chip.Fans {
+				if fan.Label == label {
+					return fan.RPM, true
+				}
+			}
+		case "sensor.voltage":
+			for _, voltage := range chip.Voltages {
+				if voltage.Label == label {
+					return voltage.Value, true
+				}
+			}
+		}
+	}
+	if key == "sensor.temperature" {
+		for _, zone := range sensors.ThermalZones {
+			if zone.Name == arg || zone.Type == arg {
+				return zone.Temperature, true
+			}
+		}
+	}
+	return 0, false
+}
+
+func cgroupValue(cgroups *models.CgroupMetrics, key, path string) (float64, bool) {
+	if cgroups == nil {
+		return 0, false
+	}
+	stats := cgroups.Self
+	if path != "self" {
+		stats = nil
+		for i := range cgroups.Cgroups {
+			if cgroups.Cgroups[i].Path == path {
+				stats = &cgroups.Cgroups[i]
+				break
+			}
+		}
+	}
+	if stats == nil {
+		return 0, false
+	}
+	switch key {
+	case "cgroup.cpu_percent":
+		return stats.CPUPercent, true
+	case "cgroup.throttling":
+		return stats.ThrottledPercent, true
+	case "cgroup.pids":
+		if stats.PIDs == nil {
+			return 0, false
+		}
+		return float64(stats.PIDs.Current), true
+	}
+	if stats.Memory == nil {
+		return 0, false
+	}
+	switch key {
+	case "cgroup.memory_usage":
+		return stats.Memory.Usage, stats.Memory.Max != nil
+	case "cgroup.memory_current":
+		return float64(stats.Memory.Current), true
+	default:
+		return float64(stats.Memory.NewOOMKills), true
+	}
+}
+
+func interfaceValue(interfaces []models.NetworkInterface, name, field string) (float64, bool) {
+	for _, iface := range interfaces {
+		if iface.Name != name {
+			continue
+		}
+		switch field {
+		case "up":
+			if iface.OperState == "up" {
+				return 1, true
+			}
+			return 0, true
+		case "rx_rate":
+			return iface.RxRate, true
+		case "tx_rate":
+			return iface.TxRate, true
+		case "rx_errors":
+			return iface.RxErrorRate, true
+		case "tx_errors":
+			return iface.TxErrorRate, true
+		case "rx_dropped":
+			return iface.RxDropRate, true
+		case "tx_dropped":
+			return iface.TxDropRate, true
+		}
+	}
+	return 0, false
+}
+
+// resourcePressure resolves "<resource>.<kind>.<avg>" part of psi metric keys
+func resourcePressure(key string, cpu, memory, io *models.PressureResource) (float64, bool) {
+	parts := strings.Split(key, ".")
+	if len(parts) != 3 {
+		return 0, false
+	}
+	switch parts[0] {
+	case "cpu":
+		return pressureValue(cpu, parts[1], parts[2])
+	case "memory":
+		return pressureValue(memory, parts[1], parts[2])
+	case "io":
+		return pressureValue(io, parts[1], parts[2])
+	}
+	return 0, false
+}
+
+func (as *AlertService) countInWindow(times []time.Time, window string, now time.Time) float64 {
+	duration, _ := time.ParseDuration(window)
+	count := 0
+	for _, t := range times {
+		if now.Sub(t) <= duration {
+			count++
+		}
+	}
+	return float64(count)
+}
+
+// recordEvents remembers times of process starts and group restarts used in windowed rules
+// and drops ones older than any window
+func (as *AlertService) recordEvents(rules []models.AlertRule, events []models.Event, now time.Time) {
+	watchedStarts := make(map[string]time.Duration)
+	watchedGroups := make(map[string]time.Duration)
+	for _, rule := range rules {
+		key, arg, _ := strings.Cut(rule.Metric, ":")
+		window, _ := time.ParseDuration(rule.Window)
+		switch key {
+		case "process.restarts":
+			watchedStarts[arg] = max(watchedStarts[arg], window)
+		case "group.restarts":
+			watchedGroups[arg] = max(watchedGroups[arg], window)
+		}
+	}
+
+	for _, event := range events {
+		switch {
+		case event.Type == models.EventProcessStarted && event.Process != nil:
+			if _, ok := watchedStarts[event.Process.Name]; ok {
+				as.rules.starts[event.Process.Name] = append(as.rules.starts[event.Process.Name], event.Timestamp)
+			}
+		case event.Type == models.EventGroupRestarted:
+			if _, ok := watchedGroups[event.Group]; ok {
+				as.rules.groupRestarts[event.Group] = append(as.rules.groupRestarts[event.Group], event.Timestamp)
+			}
+		}
+	}
+
+	pruneEventTimes(as.rules.starts, watchedStarts, now)
+	pruneEventTimes(as.rules.groupRestarts, watchedGroups, now)
+}
+
+func pruneEventTimes(times map[string][]time.Time, windows map[string]time.Duration, now time.Time) {
+	for name, list := range times {
+		window, ok := windows[name]
+		if !ok {
+			delete(times, name)
+			continue
+		}
+		kept := list[:0]
+		for _, t := range list {
+			if now.Sub(t) <= window {
+				kept = append(kept, t)
+			}
+		}
+		times[name] = kept
+	}
+}
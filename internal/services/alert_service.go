@@ -8,12 +8,17 @@ import (
 	"time"
 )
 
+// sensorCritMargin is how many degrees below its own critical threshold a sensor raises a warning
+const sensorCritMargin = 5.0
+
 type AlertService struct {
 	mu           sync.Mutex
 	alerts       []models.Alert
 	maxAlerts    int
 	config       models.AlertConfig
 	activeAlerts map[string]bool
+	sensorLevels map[string]string // level of active sensor alerts, a warning that turns critical is raised again
+	rules        ruleState
 }
 
 func NewAlertService() *AlertService {
@@ -21,6 +26,8 @@ func NewAlertService() *AlertService {
 		alerts:       make([]models.Alert, 0),
 		maxAlerts:    50,
 		activeAlerts: make(map[string]bool),
+		sensorLevels: make(map[string]string),
+		rules:        ruleState{starts: make(map[string][]time.Time), groupRestarts: make(map[string][]time.Time)},
 		config: models.AlertConfig{
 			CPUTreshold:  75.0,
 			RAMTreshold:  75.0,
@@ -83,6 +90,9 @@ func (as *AlertService) CheckMetrics(metrics models.SystemMetrics) []models.Aler
 		as.resolveAlert("DISK")
 	}
 
+	newAlerts = append(newAlerts, as.checkSensors(metrics.Sensors, now)...)
+	newAlerts = append(newAlerts, as.checkRules(metrics, now)...)
+
 	if len(newAlerts) > 0 {
 		as.addAlert(newAlerts)
 	}
@@ -90,6 +100,72 @@ func (as *AlertService) CheckMetrics(metrics models.SystemMetrics) []models.Aler
 	return newAlerts
 }
 
+// checkSensors alerts on temperatures near the critical threshold their driver reports,
+// sensors without one are left to custom rules
+func (as *AlertService) checkSensors(sensors *models.Sensors, now time.Time) []models.Alert {
+	// ClearHistory replaces the maps from an api request while a collection may be running
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	var newAlerts []models.Alert
+	seen := make(map[string]bool)
+	check := func(name string, value float64, crit *float64) {
+		if crit == nil {
+			return
+		}
+		alertType := "SENSOR:" + name
+		seen[alertType] = true
+
+		level := ""
+		switch {
+		case value >= *crit:
+			level = "critical"
+		case value >= *crit-sensorCritMargin:
+			level = "warning"
+		}
+		if level == "" {
+			as.resolveAlert(alertType)
+			delete(as.sensorLevels, alertType)
+			return
+		}
+		if as.activeAlerts[alertType] && (as.sensorLevels[alertType] == level || level == "warning") {
+			return
+		}
+
+		as.activeAlerts[alertType] = true
+		as.sensorLevels[alertType] = level
+		newAlerts = append(newAlerts, models.Alert{
+			ID:        generateID(alertType, now),
+			Type:      alertType,
+			Message:   fmt.Sprintf("[%s] %s: temperature %.1f°C (critical at %.1f°C)\n", name, level, value, *crit),
+			Level:     level,
+			Threshold: *crit,
+			Timestamp: now,
+			Active:    true,
+		})
+	}
+
+	if sensors != nil {
+		for _, chip := range sensors.Chips {
+			for _, temperature := range chip.Temperatures {
+				check(chip.ID+"/"+temperature.Label, temperature.Value, temperature.Crit)
+			}
+		}
+		for _, zone := range sensors.ThermalZones {
+			check(zone.Name, zone.Temperature, zone.Crit)
+		}
+	}
+
+	// a sensor that disappeared, e.g. a removed drive, no longer has an alert
+	for alertType := range as.sensorLevels {
+		if !seen[alertType] {
+			as.resolveAlert(alertType)
+			delete(as.sensorLevels, alertType)
+		}
+	}
+	return newAlerts
+}
+
 func (as *AlertService) generateAlertMessage(alertType string, value, threshold float64, level string) string {
 	switch alertType {
 	case "CPU":
@@ -156,12 +232,34 @@ func (as *AlertService) GetAlertHistory() models.AlertHistory {
 	return history
 }
 
-func (as *AlertService) UpdateConfig(config models.AlertConfig) {
+// UpdateConfig replaces alert config, rules are kept when config has none (older clients don't know about them)
+func (as *AlertService) UpdateConfig(config models.AlertConfig) error {
+	if err := ValidateAlertRules(config.Rules); err != nil {
+		return err
+	}
+
 	as.mu.Lock()
 	defer as.mu.Unlock()
 
+	if config.Rules == nil {
+		config.Rules = as.config.Rules
+	}
 	as.config = config
-	log.Printf("🪪 Alert config updated: CPU = %.1f%%, RAM = %.1f%%, Disk = %.1f%%, Enabled = %v\n", config.CPUTreshold, config.RAMTreshold, config.DiskTreshold, config.Enabled)
+	log.Printf("🪪 Alert config updated: CPU = %.1f%%, RAM = %.1f%%, Disk = %.1f%%, Enabled = %v, Rules = %d\n", config.CPUTreshold, config.RAMTreshold, config.DiskTreshold, config.Enabled, len(config.Rules))
+	return nil
+}
+
+// SetRules replaces custom alert rules, other settings stay the same
+func (as *AlertService) SetRules(rules []models.AlertRule) error {
+	if err := ValidateAlertRules(rules); err != nil {
+		return err
+	}
+
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	as.config.Rules = rules
+	return nil
 }
 
 func (as *AlertService) GetConfig() models.AlertConfig {
@@ -177,6 +275,7 @@ func (as *AlertService) ClearHistory() {
 
 	as.alerts = make([]models.Alert, 0)
 	as.activeAlerts = make(map[string]bool)
+	as.sensorLevels = make(map[string]string)
 
 	log.Printf("✅ Alert history is clear")
 }
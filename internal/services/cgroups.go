@@ -0,0 +1,315 @@
+package services
+
+import (
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syspulse/internal/models"
+	"time"
+)
+
+// cgroupSample keeps counters of one cgroup from previous collection for rates
+type cgroupSample struct {
+	cpuUsage  uint64
+	periods   uint64
+	throttled uint64
+	oomKills  uint64
+	io        map[string]models.CgroupIO
+}
+
+// cgroupCollector reads cgroup v2 controller files of the own cgroup and of chosen cgroups
+type cgroupCollector struct {
+	sysRoot  string
+	root     string   // cgroup v2 mount, empty when there is none
+	patterns []string // globs of cgroup paths relative to root
+	self     string   // own cgroup path, "/" inside a cgroup namespace
+	devices  map[string]string
+	prev     map[string]cgroupSample
+	prevTime time.Time
+}
+
+func newCgroupCollector(sysRoot, cgroups string) *cgroupCollector {
+	cc := &cgroupCollector{sysRoot: sysRoot, devices: make(map[string]string), prev: make(map[string]cgroupSample)}
+	root, ok := cgroupV2Root(sysRoot)
+	if !ok {
+		log.Printf("ℹ️ cgroup v2 is not mounted, cgroup metrics are not available")
+		return cc
+	}
+	cc.root = root
+	for _, pattern := range splitPatterns(cgroups) {
+		if pattern = strings.Trim(pattern, "/"); pattern != "" {
+			cc.patterns = append(cc.patterns, pattern)
+		}
+	}
+	// our own /proc, not SYS_PULSE_PROC_ROOT, whose pid namespace may be another one
+	if self, err := readProcCgroup("/proc", int32(os.Getpid())); err == nil && strings.HasPrefix(self, "/") {
+		cc.self = self
+	}
+	return cc
+}
+
+// collect returns nil without cgroup v2
+func (cc *cgroupCollector) collect() *models.CgroupMetrics {
+	if cc.root == "" {
+		return nil
+	}
+	now := time.Now()
+	elapsed := now.Sub(cc.prevTime).Seconds()
+	current := make(map[string]cgroupSample)
+	read := func(path string) *models.CgroupStats {
+		prev, hasPrev := cc.prev[path]
+		stats, sample, ok := cc.readCgroup(path, prev, hasPrev && elapsed > 0, elapsed)
+		if !ok {
+			return nil
+		}
+		current[path] = sample
+		return stats
+	}
+
+	metrics := &models.CgroupMetrics{}
+	if cc.self != "" {
+		metrics.Self = read(cc.self)
+	}
+	seen := make(map[string]bool)
+	for _, pattern := range cc.patterns {
+		matches, _ := filepath.Glob(filepath.Join(cc.root, pattern))
+		for _, dir := range matches {
+			path := "/" + strings.TrimPrefix(strings.TrimPrefix(dir, cc.root), "/")
+			if seen[path] {
+				continue
+			}
+			seen[path] = true
+			if stats := read(path); stats != nil {
+				metrics.Cgroups = append(metrics.Cgroups, *stats)
+			}
+		}
+	}
+	sort.Slice(metrics.Cgroups, func(i, j int) bool { return metrics.Cgroups[i].Path < metrics.Cgroups[j].Path })
+
+	// cgroups that are gone are forgotten, a new one with the same path starts without rates
+	cc.prev = current
+	cc.prevTime = now
+	return metrics
+}
+
+// readCgroup returns false when path is not a cgroup, e.g. a plain file matched by a glob.
+// Rates are computed when hasPrev is set, prev was read elapsed seconds ago
+func (cc *cgroupCollector) readCgroup(path string, prev cgroupSample, hasPrev bool, elapsed float64) (*models.CgroupStats, cgroupSample, bool) {
+	dir := filepath.Join(cc.root, path)
+	cpuStat, err := readKeyValueFile(filepath.Join(dir, "cpu.stat"))
+	if err != nil {
+		return nil, cgroupSample{}, false
+	}
+
+	stats := &models.CgroupStats{
+		Path:             path,
+		CPUUsage:         cpuStat["usage_usec"],
+		ThrottledPeriods: cpuStat["nr_throttled"],
+		ThrottledTime:    cpuStat["throttled_usec"],
+	}
+	sample := cgroupSample{cpuUsage: cpuStat["usage_usec"], periods: cpuStat["nr_periods"], throttled: cpuStat["nr_throttled"]}
+	if hasPrev {
+		stats.CPUPercent = float64(counterDelta(prev.cpuUsage, sample.cpuUsage)) / (elapsed * 1000000) * 100
+		if periods := counterDelta(prev.periods, sample.periods); periods > 0 {
+			stats.ThrottledPercent = float64(counterDelta(prev.throttled, sample.throttled)) / float64(periods) * 100
+		}
+	}
+
+	// cpu.max is "max 100000" or "<quota> <period>" in microseconds
+	if fields := strings.Fields(readSysfsString(filepath.Join(dir, "cpu.max"))); len(fields) == 2 && fields[0] != "max" {
+		quota, err1 := strconv.ParseFloat(fields[0], 64)
+		period, err2 := strconv.ParseFloat(fields[1], 64)
+		if err1 == nil && err2 == nil && period > 0 {
+			stats.CPULimit = quota / period
+		}
+	}
+	stats.CPUs = countCPUList(readSysfsString(filepath.Join(dir, "cpuset.cpus.effective")))
+
+	if memory := cc.readMemory(dir); memory != nil {
+		sample.oomKills = memory.Events.OOMKill
+		if hasPrev {
+			memory.NewOOMKills = counterDelta(prev.oomKills, sample.oomKills)
+		}
+		stats.Memory = memory
+	}
+
+	stats.IO, sample.io = cc.readIO(dir, prev.io, hasPrev, elapsed)
+
+	if current, ok := readCgroupValue(filepath.Join(dir, "pids.current")); ok {
+		stats.PIDs = &models.CgroupPIDs{Current: current}
+		if limit, ok := readCgroupValue(filepath.Join(dir, "pids.max")); ok {
+			stats.PIDs.Max = &limit
+		}
+	}
+	return stats, sample, true
+}
+
+// readMemory returns nil when memory controller is not enabled for the cgroup, which is always so for the root
+func (cc *cgroupCollector) readMemory(dir string) *models.CgroupMemory {
+	current, ok := readCgroupValue(filepath.Join(dir, "memory.current"))
+	if !ok {
+		return nil
+	}
+	memory := &models.CgroupMemory{Current: current, WorkingSet: current}
+	if limit, ok := readCgroupValue(filepath.Join(dir, "memory.max")); ok {
+		memory.Max = &limit
+	}
+	if high, ok := readCgroupValue(filepath.Join(dir, "memory.high")); ok {
+		memory.High = &high
+	}
+	if stat, err := readKeyValueFile(filepath.Join(dir, "memory.stat")); err == nil {
+		memory.Anon = stat["anon"]
+		memory.File = stat["file"]
+		memory.Shmem = stat["shmem"]
+		memory.SlabReclaimable = stat["slab_reclaimable"]
+		memory.SlabUnreclaimable = stat["slab_unreclaimable"]
+		memory.Dirty = stat["file_dirty"]
+		memory.Writeback = stat["file_writeback"]
+		memory.WorkingSet = current - min(current, stat["inactive_file"])
+	}
+	if memory.Max != nil && *memory.Max > 0 {
+		memory.Usage = float64(memory.WorkingSet) / float64(*memory.Max) * 100
+	}
+	memory.SwapCurrent, _ = readCgroupValue(filepath.Join(dir, "memory.swap.current"))
+	if limit, ok := readCgroupValue(filepath.Join(dir, "memory.swap.max")); ok {
+		memory.SwapMax = &limit
+	}
+	if events, err := readKeyValueFile(filepath.Join(dir, "memory.events")); err == nil {
+		memory.Events = models.CgroupMemoryEvents{
+			Low:     events["low"],
+			High:    events["high"],
+			Max:     events["max"],
+			OOM:     events["oom"],
+			OOMKill: events["oom_kill"],
+		}
+	}
+	return memory
+}
+
+// readIO parses io.stat lines like "8:0 rbytes=1459200 wbytes=314773504 rios=192 wios=353 dbytes=0 dios=0"
+func (cc *cgroupCollector) readIO(dir string, prev map[string]models.CgroupIO, hasPrev bool, elapsed float64) ([]models.CgroupIO, map[string]models.CgroupIO) {
+	data, err := os.ReadFile(filepath.Join(dir, "io.stat"))
+	if err != nil {
+		return nil, nil
+	}
+	var devices []models.CgroupIO
+	current := make(map[string]models.CgroupIO)
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		io := models.CgroupIO{Device: cc.deviceName(fields[0])}
+		for _, field := range fields[1:] {
+			key, value, _ := strings.Cut(field, "=")
+			number, _ := strconv.ParseUint(value, 10, 64)
+			switch key {
+			case "rbytes":
+				io.ReadBytes = number
+			case "wbytes":
+				io.WriteBytes = number
+			case "rios":
+				io.ReadOps = number
+			case "wios":
+				io.WriteOps = number
+			}
+		}
+		if before, ok := prev[fields[0]]; ok && hasPrev {
+			io.ReadRate = float64(counterDelta(before.ReadBytes, io.ReadBytes)) / elapsed
+			io.WriteRate = float64(counterDelta(before.WriteBytes, io.WriteBytes)) / elapsed
+		}
+		current[fields[0]] = io
+		devices = append(devices, io)
+	}
+	sort.Slice(devices, func(i, j int) bool { return devices[i].Device < devices[j].Device })
+	return devices, current
+}
+
+// deviceName resolves major:minor with the /sys/dev/block link, names are cached as devices rarely change
+func (cc *cgroupCollector) deviceName(number string) string {
+	if name, ok := cc.devices[number]; ok {
+		return name
+	}
+	name := number
+	if target, err := os.Readlink(filepath.Join(cc.sysRoot, "dev", "block", number)); err == nil {
+		name = filepath.Base(target)
+	}
+	cc.devices[number] = name
+	return name
+}
+
+// readCgroupValue reads a single number file, "max" means no limit and reads as not set
+func readCgroupValue(path string) (uint64, bool) {
+	value, err := strconv.ParseUint(readSysfsString(path), 10, 64)
+	return value, err == nil
+}
+
+// countCPUList counts cpus in a list like "0-3,8,10-11"
+func countCPUList(list string) int {
+	count := 0
+	for _, part := range strings.Split(list, ",") {
+		first, last, isRange := strings.Cut(strings.TrimSpace(part), "-")
+		start, err := strconv.Atoi(first)
+		if err != nil {
+			continue
+		}
+		end := start
+		if isRange {
+			if end, err = strconv.Atoi(last); err != nil || end < start {
+				continue
+			}
+		}
+		count += end - start + 1
+	}
+	return count
+}
+
+// applyContainerLimits replaces host cpu usage and memory with those of the own cgroup,
+// so dashboards and default alerts see the limits the container actually runs under
+func applyContainerLimits(cpuInfo *models.CPUInfo, memory *models.MemInfo, self *models.CgroupStats) {
+	cores := self.CPULimit
+	if cores == 0 && self.CPUs > 0 {
+		cores = float64(self.CPUs)
+	}
+	if cores == 0 {
+		cores = float64(cpuInfo.Cores)
+	}
+	if cores > 0 {
+		cpuInfo.Usage = min(self.CPUPercent/cores, 100)
+		cpuInfo.Cores = int(math.Ceil(cores))
+		cpuInfo.Container = true
+	}
+
+	cg := self.Memory
+	if cg == nil || cg.Max == nil || memory.Error != "" || (memory.Total > 0 && *cg.Max >= memory.Total) {
+		return // no memory limit below the host memory, host numbers are the right ones
+	}
+	memory.Total = *cg.Max
+	memory.Used = min(cg.WorkingSet, memory.Total)
+	memory.Available = memory.Total - memory.Used
+	memory.Free = memory.Total - min(cg.Current, memory.Total)
+	memory.Usage = cg.Usage
+	memory.Buffers = 0
+	memory.Cached = cg.File
+	memory.Shared = cg.Shmem
+	memory.SlabReclaimable = cg.SlabReclaimable
+	memory.SlabUnreclaimable = cg.SlabUnreclaimable
+	memory.Dirty = cg.Dirty
+	memory.Writeback = cg.Writeback
+	memory.OOMKills = cg.Events.OOMKill
+	memory.NewOOMKills = cg.NewOOMKills
+	if cg.SwapMax != nil && memory.Swap.Error == "" {
+		memory.Swap.Total = min(*cg.SwapMax, memory.Swap.Total)
+		memory.Swap.Used = min(cg.SwapCurrent, memory.Swap.Total)
+		memory.Swap.Free = memory.Swap.Total - memory.Swap.Used
+		memory.Swap.Usage = 0
+		if memory.Swap.Total > 0 {
+			memory.Swap.Usage = float64(memory.Swap.Used) / float64(memory.Swap.Total) * 100
+		}
+	}
+	memory.Container = true
+}
@@ -0,0 +1,168 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"syspulse/internal/models"
+	"testing"
+	"time"
+)
+
+const mib = 1 << 20
+
+func TestCgroupCollector(t *testing.T) {
+	sysRoot := fakeSysfs(t, map[string]string{
+		"fs/cgroup/system.slice/app.service/cpu.stat":              "usage_usec 1000000\nuser_usec 800000\nsystem_usec 200000\nnr_periods 100\nnr_throttled 10\nthrottled_usec 5000",
+		"fs/cgroup/system.slice/app.service/cpu.max":               "150000 100000",
+		"fs/cgroup/system.slice/app.service/cpuset.cpus.effective": "0-3,8,10-11",
+		"fs/cgroup/system.slice/app.service/memory.current":        "629145600", // 600 MiB
+		"fs/cgroup/system.slice/app.service/memory.max":            "1073741824",
+		"fs/cgroup/system.slice/app.service/memory.high":           "max",
+		"fs/cgroup/system.slice/app.service/memory.stat":           "anon 314572800\nfile 209715200\ninactive_file 104857600\nshmem 1048576",
+		"fs/cgroup/system.slice/app.service/memory.swap.current":   "0",
+		"fs/cgroup/system.slice/app.service/memory.swap.max":       "536870912",
+		"fs/cgroup/system.slice/app.service/memory.events":         "low 0\nhigh 0\nmax 4\noom 1\noom_kill 1",
+		"fs/cgroup/system.slice/app.service/io.stat":               "8:0 rbytes=1048576 wbytes=2097152 rios=10 wios=20 dbytes=0 dios=0\n259:0 rbytes=0 wbytes=4096 rios=0 wios=1 dbytes=0 dios=0",
+		"fs/cgroup/system.slice/app.service/pids.current":          "12",
+		"fs/cgroup/system.slice/app.service/pids.max":              "max",
+
+		"fs/cgroup/system.slice/db.service/cpu.stat":       "usage_usec 0\nnr_periods 0\nnr_throttled 0\nthrottled_usec 0",
+		"fs/cgroup/system.slice/db.service/cpu.max":        "max 100000",
+		"fs/cgroup/system.slice/db.service/memory.current": "104857600",
+		"fs/cgroup/system.slice/db.service/memory.max":     "max",
+		"fs/cgroup/system.slice/db.service/pids.current":   "3",
+		"fs/cgroup/system.slice/db.service/pids.max":       "100",
+
+		"fs/cgroup/system.slice/cgroup.procs": "", // matched by the glob, not a cgroup
+	}, map[string]string{
+		"dev/block/8:0": "../../devices/pci0000:00/0000:00:17.0/ata1/host0/target0:0:0/0:0:0:0/block/sda",
+	})
+	cc := &cgroupCollector{
+		sysRoot:  sysRoot,
+		root:     filepath.Join(sysRoot, "fs", "cgroup"),
+		patterns: []string{"system.slice/*"},
+		devices:  make(map[string]string),
+		prev:     make(map[string]cgroupSample),
+	}
+
+	metrics := cc.collect()
+	if metrics == nil || metrics.Self != nil || len(metrics.Cgroups) != 2 {
+		t.Fatalf("cgroups = %+v, want app and db", metrics)
+	}
+	app, db := metrics.Cgroups[0], metrics.Cgroups[1]
+
+	if app.Path != "/system.slice/app.service" || app.CPULimit != 1.5 || app.CPUs != 7 || app.CPUUsage != 1000000 || app.ThrottledPeriods != 10 {
+		t.Errorf("app cpu = %+v", app)
+	}
+	if app.CPUPercent != 0 || app.ThrottledPercent != 0 {
+		t.Errorf("app rates without a previous collection: cpu %.1f, throttled %.1f", app.CPUPercent, app.ThrottledPercent)
+	}
+	memory := app.Memory
+	if memory == nil || memory.Max == nil || *memory.Max != 1024*mib || memory.High != nil || memory.WorkingSet != 500*mib {
+		t.Fatalf("app memory = %+v", memory)
+	}
+	assertNear(t, "app memory usage", memory.Usage, 500.0/1024*100, 0.001)
+	if memory.File != 200*mib || memory.SwapMax == nil || *memory.SwapMax != 512*mib || memory.Events.OOMKill != 1 || memory.Events.Max != 4 {
+		t.Errorf("app memory = %+v", memory)
+	}
+	if len(app.IO) != 2 || app.IO[0].Device != "259:0" || app.IO[1].Device != "sda" || app.IO[1].ReadBytes != mib || app.IO[1].WriteOps != 20 {
+		t.Errorf("app io = %+v", app.IO)
+	}
+	if app.PIDs == nil || app.PIDs.Current != 12 || app.PIDs.Max != nil {
+		t.Errorf("app pids = %+v", app.PIDs)
+	}
+
+	if db.CPULimit != 0 || db.CPUs != 0 {
+		t.Errorf("db without cpu limit = %.2f cores, %d cpus", db.CPULimit, db.CPUs)
+	}
+	if db.Memory == nil || db.Memory.Max != nil || db.Memory.Usage != 0 || db.Memory.WorkingSet != 100*mib {
+		t.Errorf("db memory without limit = %+v", db.Memory)
+	}
+	if db.PIDs == nil || db.PIDs.Max == nil || *db.PIDs.Max != 100 || db.IO != nil {
+		t.Errorf("db pids %+v, io %+v", db.PIDs, db.IO)
+	}
+
+	// one second later: half a core used, half of the periods throttled, two more oom kills
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(cc.root, "system.slice", "app.service", name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("cpu.stat", "usage_usec 1500000\nnr_periods 150\nnr_throttled 35\nthrottled_usec 9000")
+	write("memory.events", "oom_kill 3")
+	write("io.stat", "8:0 rbytes=3145728 wbytes=2097152 rios=12 wios=20")
+	cc.prevTime = cc.prevTime.Add(-time.Second)
+
+	app = cc.collect().Cgroups[0]
+	assertNear(t, "app cpu percent", app.CPUPercent, 50, 1)
+	assertNear(t, "app throttled percent", app.ThrottledPercent, 50, 0.001)
+	assertNear(t, "app read rate", app.IO[0].ReadRate, 2*mib, 2*mib/50)
+	if app.Memory.NewOOMKills != 2 {
+		t.Errorf("new oom kills = %d, want 2", app.Memory.NewOOMKills)
+	}
+}
+
+func TestCountCPUList(t *testing.T) {
+	for list, want := range map[string]int{
+		"0-3,8,10-11": 7,
+		"0":           1,
+		"":            0,
+		"0-63":        64,
+		"3-1,5":       1, // a broken range is skipped
+	} {
+		if got := countCPUList(list); got != want {
+			t.Errorf("countCPUList(%q) = %d, want %d", list, got, want)
+		}
+	}
+}
+
+func TestApplyContainerLimits(t *testing.T) {
+	host := func() (models.CPUInfo, models.MemInfo) {
+		return models.CPUInfo{Usage: 10, Cores: 16},
+			models.MemInfo{Total: 16384 * mib, Used: 4096 * mib, Usage: 25, Cached: 8192 * mib,
+				Swap: models.SwapInfo{Total: 2048 * mib, Used: 1024 * mib, Usage: 50}}
+	}
+	uint64p := func(v uint64) *uint64 { return &v }
+
+	// 1.5 cores and 1 GiB
+	cpu, memory := host()
+	applyContainerLimits(&cpu, &memory, &models.CgroupStats{
+		CPUPercent: 75,
+		CPULimit:   1.5,
+		Memory: &models.CgroupMemory{Current: 600 * mib, WorkingSet: 500 * mib, Max: uint64p(1024 * mib), Usage: 48.8, File: 200 * mib,
+			SwapCurrent: 300 * mib, SwapMax: uint64p(256 * mib)},
+	})
+	if !cpu.Container || cpu.Cores != 2 || cpu.Usage != 50 {
+		t.Errorf("limited cpu = %+v, want 2 cores at 50%%", cpu)
+	}
+	if !memory.Container || memory.Total != 1024*mib || memory.Used != 500*mib || memory.Available != 524*mib || memory.Free != 424*mib || memory.Cached != 200*mib || memory.Usage != 48.8 {
+		t.Errorf("limited memory = %+v", memory)
+	}
+	if swap := memory.Swap; swap.Total != 256*mib || swap.Used != 256*mib || swap.Free != 0 || swap.Usage != 100 {
+		t.Errorf("limited swap = %+v, want 256 MiB all used", swap)
+	}
+
+	// memory.max above host memory and cpu.max "max" on a 4 cpu cpuset
+	cpu, memory = host()
+	applyContainerLimits(&cpu, &memory, &models.CgroupStats{
+		CPUPercent: 200,
+		CPUs:       4,
+		Memory:     &models.CgroupMemory{Current: 600 * mib, WorkingSet: 500 * mib, Max: uint64p(65536 * mib)},
+	})
+	if cpu.Cores != 4 || cpu.Usage != 50 {
+		t.Errorf("cpuset cpu = %+v, want 4 cores at 50%%", cpu)
+	}
+	if _, hostMemory := host(); memory != hostMemory {
+		t.Errorf("memory with limit above the host = %+v, want host numbers", memory)
+	}
+
+	// memory.max "max"
+	cpu, memory = host()
+	applyContainerLimits(&cpu, &memory, &models.CgroupStats{Memory: &models.CgroupMemory{Current: 600 * mib}})
+	if cpu.Cores != 16 || cpu.Usage != 0 {
+		t.Errorf("cpu without limits = %+v, want usage of 16 cores", cpu)
+	}
+	if _, hostMemory := host(); memory != hostMemory {
+		t.Errorf("memory without limit = %+v, want host numbers", memory)
+	}
+}
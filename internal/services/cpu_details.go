@@ -0,0 +1,268 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syspulse/internal/models"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/cpu"
+)
+
+// CPUStats is one snapshot of cpu time counters, usage is computed from two snapshots
+type CPUStats struct {
+	Time  time.Time
+	Total cpuCounters         // all cpus, "cpu" line of /proc/stat
+	Cores map[int]cpuCounters // logical core id -> counters, "cpuN" lines
+}
+
+// cpuCounters are cpu times in USER_HZ ticks since boot
+type cpuCounters struct {
+	User, Nice, System, Idle, IOWait, IRQ, SoftIRQ, Steal, Guest, GuestNice uint64
+}
+
+// total time, guest time is already counted in user and nice
+func (c cpuCounters) total() uint64 {
+	return c.User + c.Nice + c.System + c.Idle + c.IOWait + c.IRQ + c.SoftIRQ + c.Steal
+}
+
+// cpuStatsSource gives current counters. Replaced in tests with synthetic /proc/stat snapshots
+type cpuStatsSource func() (*CPUStats, error)
+
+// cpuDetails computes usage, per core usage and time breakdown from counters of two collections
+type cpuDetails struct {
+	procRoot  string
+	sysRoot   string
+	source    cpuStatsSource
+	modelName string
+	physical  int
+	last      cpuUsage // result of last computation, reused when counters didn't move
+}
+
+type cpuUsage struct {
+	usage   float64
+	times   *models.CPUTimes
+	perCore []models.CoreInfo
+}
+
+func newCPUDetails(procRoot, sysRoot string) *cpuDetails {
+	cd := &cpuDetails{
+		procRoot: procRoot,
+		sysRoot:  sysRoot,
+	}
+	cd.source = func() (*CPUStats, error) {
+		stats, err := readCPUStats(procRoot)
+		if err != nil {
+			return cpuStatsFromGopsutil() // no procfs, e.g. on windows and mac
+		}
+		return stats, nil
+	}
+	// model and amount of cores don't change while we run
+	if info, err := cpu.Info(); err == nil && len(info) > 0 {
+		cd.modelName = strings.TrimSpace(info[0].ModelName)
+	}
+	cd.physical, _ = cpu.Counts(false)
+	return cd
+}
+
+// compute returns usage since prev snapshot and whether current is the snapshot to compare with next time.
+// When counters didn't move yet, e.g. two collections within one tick, previous result is returned and
+// caller keeps prev. When they went back, e.g. after a vm snapshot restore, current becomes the new base
+func (cd *cpuDetails) compute(prev, current *CPUStats) (result cpuUsage, rebase bool) {
+	if prev == nil || current.Total.total() < prev.Total.total() {
+		return cd.last, true
+	}
+	if current.Total.total() == prev.Total.total() {
+		return cd.last, false
+	}
+
+	result.times = cpuTimesPercent(prev.Total, current.Total)
+	result.usage = 100 - result.times.Idle - result.times.IOWait
+
+	frequencies := readCoreFrequencies(cd.procRoot, cd.sysRoot)
+	ids := make([]int, 0, len(current.Cores))
+	for id := range current.Cores {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	result.perCore = make([]models.CoreInfo, 0, len(ids))
+	for _, id := range ids {
+		core := models.CoreInfo{ID: id, Frequency: frequencies[id]}
+		// a core that went offline and back has no usable prev counters
+		if prevCore, found := prev.Cores[id]; found && current.Cores[id].total() > prevCore.total() {
+			times := cpuTimesPercent(prevCore, current.Cores[id])
+			core.Usage = 100 - times.Idle - times.IOWait
+		}
+		result.perCore = append(result.perCore, core)
+	}
+
+	cd.last = result
+	return result, true
+}
+
+// cpuTimesPercent turns growth of time counters into shares of the elapsed cpu time, total must grow.
+// iowait is known to go back a little on some kernels, a counter that went back adds nothing
+func cpuTimesPercent(prev, current cpuCounters) *models.CPUTimes {
+	growth := func(prev, current uint64) uint64 {
+		if current < prev {
+			return 0
+		}
+		return current - prev
+	}
+	delta := cpuCounters{
+		User: growth(prev.User, current.User), Nice: growth(prev.Nice, current.Nice), System: growth(prev.System, current.System),
+		Idle: growth(prev.Idle, current.Idle), IOWait: growth(prev.IOWait, current.IOWait), IRQ: growth(prev.IRQ, current.IRQ),
+		SoftIRQ: growth(prev.SoftIRQ, current.SoftIRQ), Steal: growth(prev.Steal, current.Steal),
+		Guest: growth(prev.Guest, current.Guest), GuestNice: growth(prev.GuestNice, current.GuestNice),
+	}
+	total := float64(delta.total())
+	if total == 0 {
+		return &models.CPUTimes{}
+	}
+	share := func(value uint64) float64 {
+		return float64(value) / total * 100
+	}
+	return &models.CPUTimes{
+		User:      share(delta.User),
+		Nice:      share(delta.Nice),
+		System:    share(delta.System),
+		Idle:      share(delta.Idle),
+		IOWait:    share(delta.IOWait),
+		IRQ:       share(delta.IRQ),
+		SoftIRQ:   share(delta.SoftIRQ),
+		Steal:     share(delta.Steal),
+		Guest:     share(delta.Guest),
+		GuestNice: share(delta.GuestNice),
+	}
+}
+
+func readCPUStats(procRoot string) (*CPUStats, error) {
+	data, err := os.ReadFile(filepath.Join(procRoot, "stat"))
+	if err != nil {
+		return nil, err
+	}
+	return parseCPUStats(string(data), time.Now())
+}
+
+// parseCPUStats reads cpu lines of /proc/stat:
+// cpu  user nice system idle iowait irq softirq steal guest guest_nice
+func parseCPUStats(data string, now time.Time) (*CPUStats, error) {
+	stats := &CPUStats{Time: now, Cores: make(map[int]cpuCounters)}
+	foundTotal := false
+
+	for _, line := range strings.Split(data, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 5 || !strings.HasPrefix(fields[0], "cpu") {
+			continue
+		}
+
+		// older kernels have less columns, missing ones stay zero
+		var values [10]uint64
+		for i := 1; i < len(fields) && i <= len(values); i++ {
+			value, err := strconv.ParseUint(fields[i], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("bad value %q in %s line of stat", fields[i], fields[0])
+			}
+			values[i-1] = value
+		}
+		counters := cpuCounters{
+			User: values[0], Nice: values[1], System: values[2], Idle: values[3], IOWait: values[4],
+			IRQ: values[5], SoftIRQ: values[6], Steal: values[7], Guest: values[8], GuestNice: values[9],
+		}
+
+		if fields[0] == "cpu" {
+			stats.Total = counters
+			foundTotal = true
+			continue
+		}
+		id, err := strconv.Atoi(strings.TrimPrefix(fields[0], "cpu"))
+		if err != nil {
+			continue
+		}
+		stats.Cores[id] = counters
+	}
+
+	if !foundTotal {
+		return nil, fmt.Errorf("no cpu line in stat")
+	}
+	return stats, nil
+}
+
+// cpuStatsFromGopsutil is used where there is no procfs, seconds are turned into ticks
+func cpuStatsFromGopsutil() (*CPUStats, error) {
+	total, err := cpu.Times(false)
+	if err != nil || len(total) == 0 {
+		return nil, fmt.Errorf("failed to get cpu times: %v", err)
+	}
+	stats := &CPUStats{Time: time.Now(), Total: countersFromTimes(total[0]), Cores: make(map[int]cpuCounters)}
+
+	if cores, err := cpu.Times(true); err == nil {
+		for i, core := range cores {
+			id, err := strconv.Atoi(strings.TrimPrefix(core.CPU, "cpu"))
+			if err != nil {
+				id = i
+			}
+			stats.Cores[id] = countersFromTimes(core)
+		}
+	}
+	return stats, nil
+}
+
+func countersFromTimes(t cpu.TimesStat) cpuCounters {
+	ticks := func(seconds float64) uint64 {
+		return uint64(seconds * userHZ)
+	}
+	return cpuCounters{
+		User: ticks(t.User), Nice: ticks(t.Nice), System: ticks(t.System), Idle: ticks(t.Idle), IOWait: ticks(t.Iowait),
+		IRQ: ticks(t.Irq), SoftIRQ: ticks(t.Softirq), Steal: ticks(t.Steal), Guest: ticks(t.Guest), GuestNice: ticks(t.GuestNice),
+	}
+}
+
+// readCoreFrequencies returns MHz of each logical core from cpufreq, or from /proc/cpuinfo where there is no cpufreq
+func readCoreFrequencies(procRoot, sysRoot string) map[int]float64 {
+	frequencies := make(map[int]float64)
+
+	paths, _ := filepath.Glob(filepath.Join(sysRoot, "devices/system/cpu/cpu[0-9]*/cpufreq/scaling_cur_freq"))
+	for _, path := range paths {
+		id, err := strconv.Atoi(strings.TrimPrefix(filepath.Base(filepath.Dir(filepath.Dir(path))), "cpu"))
+		if err != nil {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		if khz, err := strconv.ParseFloat(strings.TrimSpace(string(data)), 64); err == nil {
+			frequencies[id] = khz / 1000
+		}
+	}
+	if len(frequencies) > 0 {
+		return frequencies
+	}
+
+	data, err := os.ReadFile(filepath.Join(procRoot, "cpuinfo"))
+	if err != nil {
+		return frequencies
+	}
+	id := -1
+	for _, line := range strings.Split(string(data), "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		switch strings.TrimSpace(key) {
+		case "processor":
+			id, _ = strconv.Atoi(strings.TrimSpace(value))
+		case "cpu MHz":
+			if mhz, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil && id >= 0 {
+				frequencies[id] = mhz
+			}
+		}
+	}
+	return frequencies
+}
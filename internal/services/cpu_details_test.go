@@ -0,0 +1,118 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+// newTestCPUDetails returns cpuDetails whose source gives the snapshots one by one
+func newTestCPUDetails(t *testing.T, snapshots ...string) *cpuDetails {
+	t.Helper()
+	cd := &cpuDetails{procRoot: t.TempDir(), sysRoot: t.TempDir()}
+	now := time.Now()
+	cd.source = func() (*CPUStats, error) {
+		if len(snapshots) == 0 {
+			t.Fatal("no snapshots left")
+		}
+		stats, err := parseCPUStats(snapshots[0], now)
+		snapshots = snapshots[1:]
+		now = now.Add(time.Second)
+		return stats, err
+	}
+	return cd
+}
+
+func computeNext(t *testing.T, cd *cpuDetails, prev *CPUStats) (*CPUStats, cpuUsage, bool) {
+	t.Helper()
+	current, err := cd.source()
+	if err != nil {
+		t.Fatal(err)
+	}
+	usage, rebase := cd.compute(prev, current)
+	return current, usage, rebase
+}
+
+func TestCPUDetailsUsageSplit(t *testing.T) {
+	cd := newTestCPUDetails(t,
+		"cpu  1000 0 500 8000 200 0 0 100 0 0\n"+
+			"cpu0 500 0 250 4000 100 0 0 50 0 0\n"+
+			"cpu1 500 0 250 4000 100 0 0 50 0 0\n",
+		// 400 ticks: user 100, system 50, idle 150, iowait 50, steal 50
+		"cpu  1100 0 550 8150 250 0 0 150 0 0\n"+
+			"cpu0 600 0 300 4000 150 0 0 50 0 0\n"+
+			"cpu1 500 0 250 4150 100 0 0 100 0 0\n"+
+			"cpu2 10 0 10 80 0 0 0 0 0 0\n",
+	)
+	first, _ := cd.source()
+	_, usage, ok := computeNext(t, cd, first)
+	if !ok {
+		t.Fatal("counters moved but compute is not ok")
+	}
+
+	assertNear(t, "usage", usage.usage, 50, 0.01) // iowait is idle time, steal is not
+	assertNear(t, "user", usage.times.User, 25, 0.01)
+	assertNear(t, "system", usage.times.System, 12.5, 0.01)
+	assertNear(t, "idle", usage.times.Idle, 37.5, 0.01)
+	assertNear(t, "iowait", usage.times.IOWait, 12.5, 0.01)
+	assertNear(t, "steal", usage.times.Steal, 12.5, 0.01)
+
+	if len(usage.perCore) != 3 {
+		t.Fatalf("got %d cores, want 3", len(usage.perCore))
+	}
+	for i, want := range []float64{75, 25, 0} { // cpu2 came online, it has nothing to compare with
+		if usage.perCore[i].ID != i {
+			t.Errorf("core %d has id %d", i, usage.perCore[i].ID)
+		}
+		assertNear(t, "core usage", usage.perCore[i].Usage, want, 0.01)
+	}
+}
+
+func TestCPUDetailsCounterGoingBack(t *testing.T) {
+	cd := newTestCPUDetails(t,
+		"cpu  1000 0 500 8000 200 0 0 0 0 0\n",
+		"cpu  1100 0 500 8100 200 0 0 0 0 0\n",
+		// iowait went back by 10 while the rest moved, it must not count as a whole new counter
+		"cpu  1100 0 500 8300 190 0 0 0 0 0\n",
+		// total went back, e.g. counters of a restored vm snapshot
+		"cpu  900 0 400 7000 100 0 0 0 0 0\n",
+		// 200 ticks after the restore, far below the counters before it
+		"cpu  1000 0 450 7050 100 0 0 0 0 0\n",
+	)
+	prev, _ := cd.source()
+	prev, first, _ := computeNext(t, cd, prev)
+	assertNear(t, "usage", first.usage, 50, 0.01)
+
+	prev, usage, ok := computeNext(t, cd, prev)
+	if !ok {
+		t.Fatal("idle moved but compute is not ok")
+	}
+	assertNear(t, "usage with iowait going back", usage.usage, 0, 0.01)
+	assertNear(t, "iowait going back", usage.times.IOWait, 0, 0.01)
+	assertNear(t, "idle", usage.times.Idle, 100, 0.01)
+
+	current, usage, rebase := computeNext(t, cd, prev)
+	if !rebase {
+		t.Fatal("total went back but the old counters stay the base")
+	}
+	assertNear(t, "idle after total went back", usage.times.Idle, 100, 0.01) // the last result is kept
+
+	_, usage, ok = computeNext(t, cd, current)
+	if !ok {
+		t.Fatal("counters moved after going back but compute is not ok")
+	}
+	assertNear(t, "usage after total went back", usage.usage, 75, 0.01)
+	assertNear(t, "idle after total went back", usage.times.Idle, 25, 0.01)
+}
+
+func TestParseCPUStatsOldKernel(t *testing.T) {
+	stats, err := parseCPUStats("cpu  10 20 30 40\ncpu0 10 20 30 40\nintr 1 2 3\n", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Total.total() != 100 || stats.Total.Steal != 0 || len(stats.Cores) != 1 {
+		t.Errorf("stats = %+v", stats)
+	}
+	if _, err := parseCPUStats("intr 1 2 3\n", time.Now()); err == nil {
+		t.Error("stat without cpu line is accepted")
+	}
+}
@@ -0,0 +1,91 @@
+package services
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+	"syspulse/internal/models"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/disk"
+)
+
+// diskIOCollector computes block device rates from /proc/diskstats counters of two collections
+type diskIOCollector struct {
+	prev     map[string]disk.IOCountersStat
+	prevTime time.Time
+}
+
+func newDiskIOCollector() *diskIOCollector {
+	return &diskIOCollector{prev: make(map[string]disk.IOCountersStat)}
+}
+
+// collect returns io of every block device, filesystems are used to map devices to mount points
+func (dc *diskIOCollector) collect(filesystems []models.Filesystem) []models.DiskIO {
+	counters, err := disk.IOCounters()
+	if err != nil {
+		return nil
+	}
+	now := time.Now()
+	elapsed := now.Sub(dc.prevTime)
+	mounts := deviceMountpoints(filesystems)
+
+	result := make([]models.DiskIO, 0, len(counters))
+	for name, current := range counters {
+		// loop and ram devices are noise unless something is mounted from them
+		if (strings.HasPrefix(name, "loop") || strings.HasPrefix(name, "ram")) && len(mounts[name]) == 0 {
+			continue
+		}
+
+		io := models.DiskIO{
+			Device:      name,
+			Mountpoints: mounts[name],
+			InFlight:    current.IopsInProgress,
+			ReadBytes:   current.ReadBytes,
+			WriteBytes:  current.WriteBytes,
+		}
+		if prev, ok := dc.prev[name]; ok && elapsed > 0 {
+			seconds := elapsed.Seconds()
+			millis := float64(elapsed) / float64(time.Millisecond)
+
+			reads := counterDelta(prev.ReadCount, current.ReadCount)
+			writes := counterDelta(prev.WriteCount, current.WriteCount)
+			io.ReadRate = float64(counterDelta(prev.ReadBytes, current.ReadBytes)) / seconds
+			io.WriteRate = float64(counterDelta(prev.WriteBytes, current.WriteBytes)) / seconds
+			io.ReadOps = float64(reads) / seconds
+			io.WriteOps = float64(writes) / seconds
+			if reads > 0 {
+				io.ReadAwait = float64(counterDelta(prev.ReadTime, current.ReadTime)) / float64(reads)
+			}
+			if writes > 0 {
+				io.WriteAwait = float64(counterDelta(prev.WriteTime, current.WriteTime)) / float64(writes)
+			}
+			io.QueueDepth = float64(counterDelta(prev.WeightedIO, current.WeightedIO)) / millis
+			io.Utilization = min(float64(counterDelta(prev.IoTime, current.IoTime))/millis*100, 100)
+		}
+		result = append(result, io)
+	}
+
+	dc.prev = counters
+	dc.prevTime = now
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Device < result[j].Device })
+	return result
+}
+
+// deviceMountpoints maps kernel device names to mount points, /dev/mapper links are resolved to dm-N
+func deviceMountpoints(filesystems []models.Filesystem) map[string][]string {
+	mounts := make(map[string][]string)
+	for _, fs := range filesystems {
+		if !strings.HasPrefix(fs.Device, "/dev/") {
+			continue
+		}
+		device := fs.Device
+		if resolved, err := filepath.EvalSymlinks(device); err == nil {
+			device = resolved
+		}
+		name := filepath.Base(device)
+		mounts[name] = append(mounts[name], fs.Mountpoint)
+	}
+	return mounts
+}
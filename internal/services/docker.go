@@ -0,0 +1,444 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syspulse/internal/models"
+	"time"
+)
+
+const (
+	dockerRefresh       = 10 * time.Second // the container list is read again this often, events trigger it earlier
+	dockerTimeout       = 5 * time.Second
+	dockerRetry         = 5 * time.Second // wait before reconnecting to the event stream
+	dockerMaxPending    = 1000            // events kept until the next collection
+	dockerShortIDLength = 12
+)
+
+// dockerContainer is the part of the inspect answer that is reported
+type dockerContainer struct {
+	ID           string
+	Name         string
+	RestartCount int
+	Created      time.Time
+	Config       struct {
+		Image  string
+		Labels map[string]string
+	}
+	State struct {
+		Status    string
+		Pid       int32
+		ExitCode  int
+		OOMKilled bool
+		StartedAt time.Time
+		Health    *struct{ Status string }
+	}
+	HostConfig struct {
+		NetworkMode string
+	}
+}
+
+// dockerListEntry is one container of /containers/json
+type dockerListEntry struct {
+	ID     string `json:"Id"`
+	State  string
+	Status string
+}
+
+type dockerEvent struct {
+	Type   string
+	Action string
+	Actor  struct {
+		ID         string
+		Attributes map[string]string
+	}
+	TimeNano int64 `json:"timeNano"`
+}
+
+// trackedContainer is a container as reported plus what collection needs to know about it
+type trackedContainer struct {
+	info        models.Container
+	hostNetwork bool
+}
+
+// containerSample keeps counters of one container from previous collection for rates
+type containerSample struct {
+	cgroup cgroupSample
+	rx, tx uint64
+}
+
+// dockerCollector keeps container inventory up to date from the docker engine api in background,
+// collection joins it with cgroup and network usage read from /proc and /sys
+type dockerCollector struct {
+	procRoot string
+	cgroups  *cgroupCollector
+	client   *http.Client // requests with a timeout
+	stream   *http.Client // the event stream, which never ends
+	refresh  chan struct{}
+
+	mu         sync.Mutex
+	containers map[string]trackedContainer // by full id
+	states     map[string]string           // state of inspected containers, a change or an event causes another inspect
+	pending    []models.Event
+
+	// used only during collection, which runs under collectMu
+	prev     map[string]containerSample
+	prevTime time.Time
+}
+
+// newDockerCollector returns nil when socket is empty, docker is not watched then
+func newDockerCollector(socket, procRoot string, cgroups *cgroupCollector) *dockerCollector {
+	socket = strings.TrimPrefix(socket, "unix://")
+	if socket == "" {
+		return nil
+	}
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, "unix", socket)
+		},
+	}
+	return &dockerCollector{
+		procRoot:   procRoot,
+		cgroups:    cgroups,
+		client:     &http.Client{Transport: transport, Timeout: dockerTimeout},
+		stream:     &http.Client{Transport: transport},
+		refresh:    make(chan struct{}, 1),
+		containers: make(map[string]trackedContainer),
+		states:     make(map[string]string),
+		prev:       make(map[string]containerSample),
+	}
+}
+
+// start watches docker until the process exits
+func (dc *dockerCollector) start() {
+	go dc.pollLoop()
+	go dc.eventLoop()
+}
+
+func (dc *dockerCollector) pollLoop() {
+	ticker := time.NewTicker(dockerRefresh)
+	defer ticker.Stop()
+	failing := false
+	for {
+		if err := dc.update(); err != nil {
+			if !failing {
+				log.Printf("❌ docker containers can't be listed: %v", err)
+			}
+			failing = true
+		} else if failing {
+			log.Printf("🐳 docker is reachable again")
+			failing = false
+		}
+		select {
+		case <-ticker.C:
+		case <-dc.refresh:
+		}
+	}
+}
+
+// update lists containers and inspects the new ones and those whose state changed
+func (dc *dockerCollector) update() error {
+	var list []dockerListEntry
+	if err := dc.get("/containers/json?all=1", &list); err != nil {
+		return err
+	}
+
+	dc.mu.Lock()
+	var changed []dockerListEntry
+	for _, entry := range list {
+		if state, ok := dc.states[entry.ID]; !ok || state != entry.State {
+			changed = append(changed, entry)
+		}
+	}
+	dc.mu.Unlock()
+
+	inspected := make(map[string]trackedContainer)
+	for _, entry := range changed {
+		var container dockerContainer
+		if err := dc.get("/containers/"+entry.ID+"/json", &container); err != nil {
+			continue // removed meanwhile, the next update drops it
+		}
+		inspected[entry.ID] = dc.inventory(container, entry.Status)
+	}
+
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+	listed := make(map[string]bool, len(list))
+	for _, entry := range list {
+		listed[entry.ID] = true
+		if container, ok := inspected[entry.ID]; ok {
+			dc.containers[entry.ID] = container
+			dc.states[entry.ID] = entry.State
+		} else if container, ok := dc.containers[entry.ID]; ok {
+			container.info.Status = entry.Status // "Up 5 minutes" changes without anything happening
+			dc.containers[entry.ID] = container
+		}
+	}
+	for id := range dc.containers {
+		if !listed[id] {
+			delete(dc.containers, id)
+			delete(dc.states, id)
+		}
+	}
+	return nil
+}
+
+func (dc *dockerCollector) inventory(container dockerContainer, status string) trackedContainer {
+	info := models.Container{
+		ID:           shortContainerID(container.ID),
+		Name:         strings.TrimPrefix(container.Name, "/"),
+		Image:        container.Config.Image,
+		State:        container.State.Status,
+		Status:       status,
+		RestartCount: container.RestartCount,
+		ExitCode:     container.State.ExitCode,
+		OOMKilled:    container.State.OOMKilled,
+		Created:      container.Created,
+		Labels:       container.Config.Labels,
+	}
+	if container.State.Health != nil {
+		info.Health = container.State.Health.Status
+	}
+	if container.State.Status == "running" || container.State.Status == "paused" {
+		info.StartedAt = container.State.StartedAt
+		info.PID = container.State.Pid
+		// the cgroup path is read once per inspect, it does not change while the container runs
+		if cgroup, err := readProcCgroup(dc.procRoot, info.PID); err == nil {
+			info.Cgroup = cgroup
+		}
+	}
+	return trackedContainer{info: info, hostNetwork: container.HostConfig.NetworkMode == "host"}
+}
+
+// eventLoop follows the docker event stream and turns container lifecycle events into host events
+func (dc *dockerCollector) eventLoop() {
+	filters := url.QueryEscape(`{"type":["container"]}`)
+	for {
+		if err := dc.followEvents("/events?filters=" + filters); err != nil {
+			dc.requestRefresh() // events may be lost while disconnected, the list catches up
+		}
+		time.Sleep(dockerRetry)
+	}
+}
+
+func (dc *dockerCollector) followEvents(path string) error {
+	response, err := dc.stream.Get("http://docker" + path)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("status %d", response.StatusCode)
+	}
+
+	decoder := json.NewDecoder(response.Body)
+	for {
+		var event dockerEvent
+		if err := decoder.Decode(&event); err != nil {
+			return err
+		}
+		if event.Type != "container" {
+			continue
+		}
+		if hostEvent, ok := containerEvent(event); ok {
+			dc.mu.Lock()
+			delete(dc.states, event.Actor.ID) // restart count or health may have changed with the same state
+			dc.pending = append(dc.pending, hostEvent)
+			if len(dc.pending) > dockerMaxPending {
+				dc.pending = dc.pending[len(dc.pending)-dockerMaxPending:]
+			}
+			dc.mu.Unlock()
+			dc.requestRefresh()
+		}
+	}
+}
+
+// containerEvent maps docker actions, e.g. "die" or "health_status: unhealthy", to host events
+func containerEvent(event dockerEvent) (models.Event, bool) {
+	action, detail, _ := strings.Cut(event.Action, ": ")
+	attributes := event.Actor.Attributes
+	info := &models.ContainerEventInfo{
+		ID:    shortContainerID(event.Actor.ID),
+		Name:  attributes["name"],
+		Image: attributes["image"],
+	}
+
+	var eventType, message string
+	switch action {
+	case "create":
+		eventType, message = models.EventContainerCreated, "created"
+	case "start":
+		eventType, message = models.EventContainerStarted, "started"
+	case "die":
+		eventType, message = models.EventContainerExited, "exited"
+		if code, err := strconv.Atoi(attributes["exitCode"]); err == nil {
+			info.ExitCode = &code
+			message = fmt.Sprintf("exited with code %d", code)
+		}
+	case "oom":
+		eventType, message = models.EventContainerOOM, "ran out of memory"
+	case "pause":
+		eventType, message = models.EventContainerPaused, "paused"
+	case "unpause":
+		eventType, message = models.EventContainerUnpaused, "unpaused"
+	case "health_status":
+		eventType, message = models.EventContainerHealth, "is "+detail
+		info.Health = detail
+	case "destroy":
+		eventType, message = models.EventContainerDestroyed, "removed"
+	default:
+		return models.Event{}, false
+	}
+
+	timestamp := time.Unix(0, event.TimeNano)
+	if event.TimeNano == 0 {
+		timestamp = time.Now()
+	}
+	return models.Event{
+		ID:        fmt.Sprintf("%s-%s-%d", eventType, info.ID, timestamp.UnixNano()),
+		Type:      eventType,
+		Timestamp: timestamp,
+		Message:   fmt.Sprintf("container %s (%s) %s", info.Name, info.Image, message),
+		Container: info,
+	}, true
+}
+
+func (dc *dockerCollector) requestRefresh() {
+	select {
+	case dc.refresh <- struct{}{}:
+	default: // a refresh is already requested
+	}
+}
+
+func (dc *dockerCollector) get(path string, value any) error {
+	response, err := dc.client.Get("http://docker" + path)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(response.Body, 512))
+		return fmt.Errorf("%s: status %d: %s", path, response.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return json.NewDecoder(response.Body).Decode(value)
+}
+
+// collect returns containers sorted by name with usage of running ones, and events since previous collection
+func (dc *dockerCollector) collect() ([]models.Container, []models.Event) {
+	if dc == nil {
+		return nil, nil
+	}
+	dc.mu.Lock()
+	tracked := make([]trackedContainer, 0, len(dc.containers))
+	for _, container := range dc.containers {
+		tracked = append(tracked, container)
+	}
+	events := dc.pending
+	dc.pending = nil
+	dc.mu.Unlock()
+
+	now := time.Now()
+	elapsed := now.Sub(dc.prevTime).Seconds()
+	current := make(map[string]containerSample)
+	containers := make([]models.Container, 0, len(tracked))
+	for _, container := range tracked {
+		info := container.info
+		if info.Cgroup != "" && info.State == "running" {
+			prev, hasPrev := dc.prev[info.ID]
+			if usage, sample, ok := dc.usage(info, container.hostNetwork, prev, hasPrev && elapsed > 0, elapsed); ok {
+				info.Usage = usage
+				current[info.ID] = sample
+			}
+		}
+		containers = append(containers, info)
+	}
+	dc.prev = current
+	dc.prevTime = now
+
+	sort.Slice(containers, func(i, j int) bool { return containers[i].Name < containers[j].Name })
+	return containers, events
+}
+
+func (dc *dockerCollector) usage(container models.Container, hostNetwork bool, prev containerSample, hasPrev bool, elapsed float64) (*models.ContainerUsage, containerSample, bool) {
+	if dc.cgroups.root == "" {
+		return nil, containerSample{}, false
+	}
+	stats, cgroup, ok := dc.cgroups.readCgroup(container.Cgroup, prev.cgroup, hasPrev, elapsed)
+	if !ok {
+		return nil, containerSample{}, false
+	}
+	sample := containerSample{cgroup: cgroup}
+	usage := &models.ContainerUsage{CPUPercent: stats.CPUPercent, CPULimit: stats.CPULimit}
+	if stats.Memory != nil {
+		usage.MemoryUsage = stats.Memory.WorkingSet
+		usage.MemoryPercent = stats.Memory.Usage
+		if stats.Memory.Max != nil {
+			usage.MemoryLimit = *stats.Memory.Max
+		}
+	}
+	if stats.PIDs != nil {
+		usage.PIDs = stats.PIDs.Current
+	}
+	for _, io := range stats.IO {
+		usage.BlockRead += io.ReadBytes
+		usage.BlockWrite += io.WriteBytes
+		usage.BlockReadRate += io.ReadRate
+		usage.BlockWriteRate += io.WriteRate
+	}
+
+	// in host network the namespace is the host's, its traffic is already in interfaces
+	if !hostNetwork {
+		if rx, tx, err := readNetDevTotals(filepath.Join(dc.procRoot, strconv.Itoa(int(container.PID)), "net", "dev")); err == nil {
+			usage.Network = &models.ContainerNetwork{RxBytes: rx, TxBytes: tx}
+			if hasPrev {
+				usage.Network.RxRate = float64(counterDelta(prev.rx, rx)) / elapsed
+				usage.Network.TxRate = float64(counterDelta(prev.tx, tx)) / elapsed
+			}
+			sample.rx, sample.tx = rx, tx
+		}
+	}
+	return usage, sample, true
+}
+
+// readNetDevTotals sums received and sent bytes of /proc/<pid>/net/dev, loopback excluded
+func readNetDevTotals(path string) (rx, tx uint64, err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		name, counters, found := strings.Cut(line, ":")
+		if !found || strings.TrimSpace(name) == "lo" {
+			continue
+		}
+		fields := strings.Fields(counters)
+		if len(fields) < 9 {
+			continue
+		}
+		received, _ := strconv.ParseUint(fields[0], 10, 64)
+		sent, _ := strconv.ParseUint(fields[8], 10, 64)
+		rx += received
+		tx += sent
+	}
+	return rx, tx, nil
+}
+
+func shortContainerID(id string) string {
+	if len(id) > dockerShortIDLength {
+		return id[:dockerShortIDLength]
+	}
+	return id
+}
@@ -0,0 +1,285 @@
+package services
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syspulse/internal/models"
+	"testing"
+	"time"
+)
+
+const (
+	webID = "4f1c9a7e2b3d5f60718293a4b5c6d7e8f9a0b1c2d3e4f5a6b7c8d9e0f1a2b3c4"
+	dbID  = "9e8d7c6b5a4f3e2d1c0b9a8f7e6d5c4b3a2f1e0d9c8b7a6f5e4d3c2b1a0f9e8d"
+)
+
+// fakeDocker serves the parts of the docker engine api the collector uses on a unix socket
+type fakeDocker struct {
+	socket string
+	events chan string // lines of the event stream
+
+	mu        sync.Mutex
+	list      []dockerListEntry
+	inspect   map[string]string // inspect answers by id
+	inspected []string          // ids inspected since last call of takeInspected
+}
+
+func startFakeDocker(t *testing.T) *fakeDocker {
+	t.Helper()
+	fd := &fakeDocker{
+		socket:  filepath.Join(t.TempDir(), "docker.sock"),
+		events:  make(chan string),
+		inspect: make(map[string]string),
+	}
+	listener, err := net.Listen("unix", fd.socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /containers/json", func(w http.ResponseWriter, r *http.Request) {
+		fd.mu.Lock()
+		defer fd.mu.Unlock()
+		json.NewEncoder(w).Encode(fd.list)
+	})
+	mux.HandleFunc("GET /containers/{id}/json", func(w http.ResponseWriter, r *http.Request) {
+		fd.mu.Lock()
+		defer fd.mu.Unlock()
+		id := r.PathValue("id")
+		answer, ok := fd.inspect[id]
+		if !ok {
+			http.Error(w, `{"message":"No such container: `+id+`"}`, http.StatusNotFound)
+			return
+		}
+		fd.inspected = append(fd.inspected, id)
+		w.Write([]byte(answer))
+	})
+	mux.HandleFunc("GET /events", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		for {
+			select {
+			case line := <-fd.events:
+				w.Write([]byte(line + "\n"))
+				w.(http.Flusher).Flush()
+			case <-r.Context().Done():
+				return
+			}
+		}
+	})
+
+	server := &http.Server{Handler: mux}
+	go server.Serve(listener)
+	t.Cleanup(func() { server.Close() })
+	return fd
+}
+
+// setContainer lists a container with state and status, inspect gives state as well
+func (fd *fakeDocker) setContainer(id, name, state, status, inspectState string) {
+	fd.mu.Lock()
+	defer fd.mu.Unlock()
+	for i, entry := range fd.list {
+		if entry.ID == id {
+			fd.list = append(fd.list[:i], fd.list[i+1:]...)
+			break
+		}
+	}
+	fd.list = append(fd.list, dockerListEntry{ID: id, State: state, Status: status})
+	fd.inspect[id] = `{"Id":"` + id + `","Name":"/` + name + `","RestartCount":2,"Created":"2026-10-01T08:00:00Z",` +
+		`"Config":{"Image":"` + name + `:latest","Labels":{"app":"` + name + `"}},"State":` + inspectState + `,"HostConfig":{"NetworkMode":"bridge"}}`
+}
+
+func (fd *fakeDocker) removeContainer(id string) {
+	fd.mu.Lock()
+	defer fd.mu.Unlock()
+	for i, entry := range fd.list {
+		if entry.ID == id {
+			fd.list = append(fd.list[:i], fd.list[i+1:]...)
+			break
+		}
+	}
+	delete(fd.inspect, id)
+}
+
+func (fd *fakeDocker) takeInspected() []string {
+	fd.mu.Lock()
+	defer fd.mu.Unlock()
+	inspected := fd.inspected
+	fd.inspected = nil
+	sort.Strings(inspected)
+	return inspected
+}
+
+func newTestDockerCollector(t *testing.T, fd *fakeDocker) *dockerCollector {
+	t.Helper()
+	procRoot := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(procRoot, "4242"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	cgroup := "0::/system.slice/docker-" + webID + ".scope\n"
+	if err := os.WriteFile(filepath.Join(procRoot, "4242", "cgroup"), []byte(cgroup), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return newDockerCollector("unix://"+fd.socket, procRoot, &cgroupCollector{})
+}
+
+const (
+	runningState = `{"Status":"running","Pid":4242,"StartedAt":"2026-10-01T08:00:05Z","Health":{"Status":"healthy"}}`
+	exitedState  = `{"Status":"exited","ExitCode":137,"OOMKilled":true,"StartedAt":"2026-10-01T08:00:05Z"}`
+)
+
+func TestDockerInventory(t *testing.T) {
+	fd := startFakeDocker(t)
+	fd.setContainer(webID, "web", "running", "Up 5 minutes (healthy)", runningState)
+	fd.setContainer(dbID, "db", "exited", "Exited (137) 2 minutes ago", exitedState)
+	dc := newTestDockerCollector(t, fd)
+
+	if err := dc.update(); err != nil {
+		t.Fatal(err)
+	}
+	containers, _ := dc.collect()
+	if len(containers) != 2 {
+		t.Fatalf("containers = %+v, want db and web", containers)
+	}
+
+	db, web := containers[0], containers[1]
+	if web.ID != webID[:12] || web.Name != "web" || web.Image != "web:latest" || web.State != "running" || web.Health != "healthy" {
+		t.Errorf("web = %+v", web)
+	}
+	if web.PID != 4242 || web.Cgroup != "/system.slice/docker-"+webID+".scope" || web.StartedAt.IsZero() {
+		t.Errorf("web pid %d, cgroup %q, started %v", web.PID, web.Cgroup, web.StartedAt)
+	}
+	if web.RestartCount != 2 || web.Labels["app"] != "web" || web.Status != "Up 5 minutes (healthy)" {
+		t.Errorf("web restarts %d, labels %v, status %q", web.RestartCount, web.Labels, web.Status)
+	}
+	if db.State != "exited" || db.ExitCode != 137 || !db.OOMKilled || db.PID != 0 || !db.StartedAt.IsZero() || db.Health != "" {
+		t.Errorf("db = %+v", db)
+	}
+	if inspected := fd.takeInspected(); len(inspected) != 2 {
+		t.Errorf("inspected %v, want both containers", inspected)
+	}
+
+	// only the status text changed, the list is enough
+	fd.setContainer(webID, "web", "running", "Up 6 minutes (healthy)", runningState)
+	if err := dc.update(); err != nil {
+		t.Fatal(err)
+	}
+	if inspected := fd.takeInspected(); len(inspected) != 0 {
+		t.Errorf("inspected %v without a state change", inspected)
+	}
+	if containers, _ := dc.collect(); containers[1].Status != "Up 6 minutes (healthy)" {
+		t.Errorf("web status = %q, want the listed one", containers[1].Status)
+	}
+
+	// a state change inspects the container again
+	fd.setContainer(webID, "web", "exited", "Exited (0) 1 second ago", `{"Status":"exited"}`)
+	if err := dc.update(); err != nil {
+		t.Fatal(err)
+	}
+	if inspected := fd.takeInspected(); len(inspected) != 1 || inspected[0] != webID {
+		t.Errorf("inspected %v, want web", inspected)
+	}
+	if containers, _ := dc.collect(); containers[1].State != "exited" || containers[1].PID != 0 || containers[1].Cgroup != "" {
+		t.Errorf("stopped web = %+v", containers[1])
+	}
+
+	fd.removeContainer(dbID)
+	if err := dc.update(); err != nil {
+		t.Fatal(err)
+	}
+	if containers, _ := dc.collect(); len(containers) != 1 || containers[0].Name != "web" {
+		t.Errorf("containers after removing db = %+v, want web", containers)
+	}
+	if _, ok := dc.states[dbID]; ok {
+		t.Error("state of the removed container is still kept")
+	}
+}
+
+func TestDockerEvents(t *testing.T) {
+	fd := startFakeDocker(t)
+	fd.setContainer(dbID, "db", "exited", "Exited (137) 1 second ago", exitedState)
+	dc := newTestDockerCollector(t, fd)
+	if err := dc.update(); err != nil {
+		t.Fatal(err)
+	}
+	fd.takeInspected()
+
+	go dc.followEvents("/events")
+	fd.events <- `{"Type":"network","Action":"connect","Actor":{"ID":"net1"}}`
+	fd.events <- `{"Type":"container","Action":"exec_start: sh","Actor":{"ID":"` + dbID + `"}}`
+	fd.events <- `{"Type":"container","Action":"die","Actor":{"ID":"` + dbID + `","Attributes":{"name":"db","image":"postgres:16","exitCode":"137"}},"timeNano":1791878400000000000}`
+
+	select {
+	case <-dc.refresh:
+	case <-time.After(2 * time.Second):
+		t.Fatal("die event did not request a refresh")
+	}
+
+	_, events := dc.collect()
+	if len(events) != 1 {
+		t.Fatalf("events = %+v, want the die event only", events)
+	}
+	event := events[0]
+	if event.Type != models.EventContainerExited || event.Message != "container db (postgres:16) exited with code 137" {
+		t.Errorf("event %s %q", event.Type, event.Message)
+	}
+	if event.Container == nil || event.Container.ID != dbID[:12] || event.Container.ExitCode == nil || *event.Container.ExitCode != 137 {
+		t.Errorf("event container = %+v", event.Container)
+	}
+	if !event.Timestamp.Equal(time.Unix(0, 1791878400000000000)) {
+		t.Errorf("event time = %v, want the docker one", event.Timestamp)
+	}
+	if _, events := dc.collect(); len(events) != 0 {
+		t.Errorf("events are reported again: %+v", events)
+	}
+
+	// the state is the same, the event still makes the next update inspect the container
+	if err := dc.update(); err != nil {
+		t.Fatal(err)
+	}
+	if inspected := fd.takeInspected(); len(inspected) != 1 || inspected[0] != dbID {
+		t.Errorf("inspected %v after the event, want db", inspected)
+	}
+}
+
+func TestContainerEvent(t *testing.T) {
+	for _, tc := range []struct {
+		action     string
+		attributes map[string]string
+		wantType   string
+		wantSuffix string
+	}{
+		{"create", nil, models.EventContainerCreated, "created"},
+		{"start", nil, models.EventContainerStarted, "started"},
+		{"die", map[string]string{"exitCode": "1"}, models.EventContainerExited, "exited with code 1"},
+		{"die", nil, models.EventContainerExited, "exited"},
+		{"oom", nil, models.EventContainerOOM, "ran out of memory"},
+		{"health_status: unhealthy", nil, models.EventContainerHealth, "is unhealthy"},
+		{"destroy", nil, models.EventContainerDestroyed, "removed"},
+		{"attach", nil, "", ""},
+	} {
+		event := dockerEvent{Type: "container", Action: tc.action}
+		event.Actor.ID = webID
+		event.Actor.Attributes = map[string]string{"name": "web", "image": "nginx"}
+		for key, value := range tc.attributes {
+			event.Actor.Attributes[key] = value
+		}
+
+		hostEvent, ok := containerEvent(event)
+		if tc.wantType == "" {
+			if ok {
+				t.Errorf("%s: event %+v, want none", tc.action, hostEvent)
+			}
+			continue
+		}
+		if !ok || hostEvent.Type != tc.wantType || !strings.HasSuffix(hostEvent.Message, " "+tc.wantSuffix) {
+			t.Errorf("%s: %s %q, want %s ending with %q", tc.action, hostEvent.Type, hostEvent.Message, tc.wantType, tc.wantSuffix)
+		}
+	}
+}
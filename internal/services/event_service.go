@@ -0,0 +1,63 @@
+package services
+
+import (
+	"sync"
+	"syspulse/internal/models"
+	"time"
+)
+
+const DefaultEventLimit = 100
+
+// EventService keeps history of host events, like AlertService does for alerts
+type EventService struct {
+	mu        sync.Mutex
+	events    []models.Event
+	maxEvents int
+}
+
+func NewEventService(maxEvents int) *EventService {
+	if maxEvents < 1 {
+		maxEvents = 1
+	}
+	return &EventService{
+		events:    make([]models.Event, 0),
+		maxEvents: maxEvents,
+	}
+}
+
+func (es *EventService) AddEvents(events []models.Event) {
+	if len(events) == 0 {
+		return
+	}
+
+	es.mu.Lock()
+	defer es.mu.Unlock()
+
+	es.events = append(es.events, events...)
+	if len(es.events) > es.maxEvents { // FIFO, the same as alert history
+		es.events = append([]models.Event(nil), es.events[len(es.events)-es.maxEvents:]...)
+	}
+}
+
+// GetEvents returns newest events first, eventType and since are optional filters
+func (es *EventService) GetEvents(eventType string, since time.Time, limit int) models.EventList {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+
+	if limit <= 0 {
+		limit = DefaultEventLimit
+	}
+
+	list := models.EventList{Events: []models.Event{}, Total: len(es.events)}
+	for i := len(es.events) - 1; i >= 0 && len(list.Events) < limit; i-- {
+		event := es.events[i]
+		if eventType != "" && event.Type != eventType {
+			continue
+		}
+		if !since.IsZero() && !event.Timestamp.After(since) {
+			continue
+		}
+		list.Events = append(list.Events, event)
+	}
+	return list
+}
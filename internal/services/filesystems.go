@@ -0,0 +1,222 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syspulse/internal/models"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/disk"
+)
+
+// statfs of a dead network mount can hang, such mount is reported with an error instead of blocking collection
+const filesystemUsageTimeout = 2 * time.Second
+
+// filesystemCollector reports every real mount, filtered by include and exclude globs
+type filesystemCollector struct {
+	procRoot string
+	include  []string
+	exclude  []string
+
+	mu      sync.Mutex
+	pending map[string]bool // mount points with usage call in flight, a hung one is not called again
+}
+
+func newFilesystemCollector(procRoot, include, exclude string) *filesystemCollector {
+	return &filesystemCollector{
+		procRoot: procRoot,
+		include:  splitPatterns(include),
+		exclude:  splitPatterns(exclude),
+		pending:  make(map[string]bool),
+	}
+}
+
+// collect returns all filesystems and the largest one as DiskInfo
+func (fc *filesystemCollector) collect() ([]models.Filesystem, models.DiskInfo) {
+	partitions, err := disk.Partitions(true)
+	if err != nil {
+		return nil, models.DiskInfo{Error: fmt.Sprintf("failed to list mounts: %v", err)}
+	}
+
+	// the same filesystem can be mounted many times (bind mounts), the shortest mount point is reported
+	sort.Slice(partitions, func(i, j int) bool { return len(partitions[i].Mountpoint) < len(partitions[j].Mountpoint) })
+	mounts := readMountIDs(fc.procRoot)
+	seen := make(map[string]bool)
+
+	filesystems := make([]models.Filesystem, 0, len(partitions))
+	for _, partition := range partitions {
+		if !fc.wanted(partition) {
+			continue
+		}
+		key, known := mounts[partition.Mountpoint]
+		if !known { // no mountinfo, e.g. on mac, the device name is all we have
+			key = partition.Device + "\x00" + partition.Fstype
+		}
+		if (known || partition.Device != "none") && seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		fs := models.Filesystem{
+			Device:     partition.Device,
+			Mountpoint: partition.Mountpoint,
+			Fstype:     partition.Fstype,
+			Options:    partition.Opts,
+		}
+		for _, option := range partition.Opts {
+			if option == "ro" {
+				fs.ReadOnly = true
+			}
+		}
+		if fs.Options == nil {
+			fs.Options = []string{}
+		}
+
+		usage, err := fc.usage(partition.Mountpoint)
+		if err != nil {
+			fs.Error = err.Error()
+		} else {
+			fs.Total = usage.Total
+			fs.Used = usage.Used
+			fs.Free = usage.Free
+			fs.Usage = usage.UsedPercent
+			fs.InodesTotal = usage.InodesTotal
+			fs.InodesUsed = usage.InodesUsed
+			fs.InodesFree = usage.InodesFree
+			fs.InodesUsage = usage.InodesUsedPercent
+		}
+		filesystems = append(filesystems, fs)
+	}
+	sort.Slice(filesystems, func(i, j int) bool { return filesystems[i].Mountpoint < filesystems[j].Mountpoint })
+
+	var largest *models.Filesystem
+	for i := range filesystems {
+		if filesystems[i].Error == "" && (largest == nil || filesystems[i].Total > largest.Total) {
+			largest = &filesystems[i]
+		}
+	}
+	if largest == nil {
+		return filesystems, models.DiskInfo{Error: "no readable filesystem"}
+	}
+	return filesystems, models.DiskInfo{
+		Total:      largest.Total,
+		Used:       largest.Used,
+		Free:       largest.Free,
+		Usage:      largest.Usage,
+		Mountpoint: largest.Mountpoint,
+	}
+}
+
+// wanted applies filters. Patterns are matched with mount point, device and fstype, special filesystems
+// are skipped unless include names them. The root filesystem is kept whatever its type, in a container it is overlay
+func (fc *filesystemCollector) wanted(partition disk.PartitionStat) bool {
+	matches := func(patterns []string) bool {
+		for _, pattern := range patterns {
+			for _, value := range []string{partition.Mountpoint, partition.Device, partition.Fstype} {
+				if matchPathPattern(pattern, value) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	if len(fc.include) > 0 {
+		if !matches(fc.include) {
+			return false
+		}
+	} else if isSpecialFilesystem(partition.Fstype) && partition.Mountpoint != "/" {
+		return false
+	}
+	return !matches(fc.exclude)
+}
+
+func (fc *filesystemCollector) usage(mountpoint string) (*disk.UsageStat, error) {
+	fc.mu.Lock()
+	if fc.pending[mountpoint] {
+		fc.mu.Unlock()
+		return nil, fmt.Errorf("filesystem is not responding")
+	}
+	fc.pending[mountpoint] = true // cleared when the call returns, stays while it hangs
+	fc.mu.Unlock()
+
+	type result struct {
+		usage *disk.UsageStat
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		usage, err := disk.Usage(mountpoint)
+
+		fc.mu.Lock()
+		delete(fc.pending, mountpoint)
+		fc.mu.Unlock()
+		done <- result{usage, err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.usage, r.err
+	case <-time.After(filesystemUsageTimeout):
+		return nil, fmt.Errorf("filesystem is not responding")
+	}
+}
+
+// matchPathPattern is path.Match where "/**" at the end also matches everything below, e.g. /mnt/**
+func matchPathPattern(pattern, value string) bool {
+	if prefix, ok := strings.CutSuffix(pattern, "/**"); ok {
+		if value == prefix || strings.HasPrefix(value, prefix+"/") {
+			return true
+		}
+	}
+	ok, _ := path.Match(pattern, value)
+	return ok
+}
+
+// readMountIDs maps mount points to the filesystem they show, read from the same mountinfo gopsutil uses.
+// A line looks like "36 35 0:32 /@home /home rw,relatime shared:1 - btrfs /dev/sda2 rw,subvol=/@home",
+// the filesystem is major:minor, every tmpfs has its own. Btrfs subvolumes show the major:minor of the whole
+// filesystem there, the subvol= option tells them apart
+func readMountIDs(procRoot string) map[string]string {
+	data, err := os.ReadFile(filepath.Join(procRoot, "1", "mountinfo"))
+	if err != nil {
+		if data, err = os.ReadFile(filepath.Join(procRoot, "self", "mountinfo")); err != nil {
+			return nil
+		}
+	}
+
+	mounts := make(map[string]string)
+	for _, line := range strings.Split(string(data), "\n") {
+		mount, super, found := strings.Cut(line, " - ")
+		mountFields, superFields := strings.Fields(mount), strings.Fields(super)
+		if !found || len(mountFields) < 5 || len(superFields) < 3 {
+			continue
+		}
+		id := mountFields[2]
+		for _, option := range strings.Split(superFields[2], ",") {
+			if subvolume, ok := strings.CutPrefix(option, "subvol="); ok {
+				id += " " + subvolume
+			}
+		}
+		mounts[mountinfoUnescaper.Replace(mountFields[4])] = id // a later mount over the same point hides earlier ones
+	}
+	return mounts
+}
+
+// mountinfo escapes space, tab, newline and backslash in paths as octal
+var mountinfoUnescaper = strings.NewReplacer(`\040`, " ", `\011`, "\t", `\012`, "\n", `\134`, `\`)
+
+func splitPatterns(value string) []string {
+	var patterns []string
+	for _, pattern := range strings.Split(value, ",") {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			patterns = append(patterns, pattern)
+		}
+	}
+	return patterns
+}
@@ -0,0 +1,64 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/shirou/gopsutil/v3/disk"
+)
+
+func TestFilesystemWantedKeepsRoot(t *testing.T) {
+	fc := newFilesystemCollector(t.TempDir(), "", "/snap/**")
+	for _, tc := range []struct {
+		partition disk.PartitionStat
+		want      bool
+	}{
+		{disk.PartitionStat{Device: "overlay", Mountpoint: "/", Fstype: "overlay"}, true},
+		{disk.PartitionStat{Device: "overlay", Mountpoint: "/var/lib/docker/overlay2/x/merged", Fstype: "overlay"}, false},
+		{disk.PartitionStat{Device: "tmpfs", Mountpoint: "/run", Fstype: "tmpfs"}, false},
+		{disk.PartitionStat{Device: "/dev/sda1", Mountpoint: "/home", Fstype: "ext4"}, true},
+		{disk.PartitionStat{Device: "/dev/loop0", Mountpoint: "/snap/core/1", Fstype: "squashfs"}, false},
+	} {
+		if got := fc.wanted(tc.partition); got != tc.want {
+			t.Errorf("wanted(%s on %s) = %v, want %v", tc.partition.Fstype, tc.partition.Mountpoint, got, tc.want)
+		}
+	}
+}
+
+func TestReadMountIDs(t *testing.T) {
+	procRoot := t.TempDir()
+	mountinfo := `22 1 0:30 /@ / rw,relatime shared:1 - btrfs /dev/sda2 rw,ssd,space_cache=v2,subvolid=256,subvol=/@
+23 22 0:30 /@home /home rw,relatime shared:2 - btrfs /dev/sda2 rw,ssd,space_cache=v2,subvolid=257,subvol=/@home
+24 22 0:30 /@home/user/share /srv/my\040share rw,relatime shared:2 - btrfs /dev/sda2 rw,ssd,subvolid=257,subvol=/@home
+25 22 8:1 / /boot rw,relatime shared:3 - ext4 /dev/sda1 rw
+26 22 0:25 / /run rw,nosuid,nodev shared:4 - tmpfs tmpfs rw,mode=755
+27 22 0:27 / /dev/shm rw,nosuid,nodev shared:5 - tmpfs tmpfs rw
+28 22 0:25 /lock /var/lock rw,nosuid,nodev shared:4 - tmpfs tmpfs rw,mode=755
+`
+	if err := os.MkdirAll(filepath.Join(procRoot, "self"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(procRoot, "self", "mountinfo"), []byte(mountinfo), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	mounts := readMountIDs(procRoot)
+	for _, tc := range []struct {
+		a, b string
+		same bool
+	}{
+		{"/", "/home", false},            // btrfs subvolumes
+		{"/home", "/srv/my share", true}, // bind mount of a directory in /home
+		{"/run", "/dev/shm", false},      // two tmpfs
+		{"/run", "/var/lock", true},      // bind mount of a directory in /run
+		{"/boot", "/run", false},
+	} {
+		if mounts[tc.a] == "" || mounts[tc.b] == "" {
+			t.Fatalf("mounts = %v, want %s and %s", mounts, tc.a, tc.b)
+		}
+		if same := mounts[tc.a] == mounts[tc.b]; same != tc.same {
+			t.Errorf("%s is %q and %s is %q, same filesystem %v, want %v", tc.a, mounts[tc.a], tc.b, mounts[tc.b], same, tc.same)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package services
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"syspulse/internal/models"
+	"time"
+)
+
+const (
+	defaultHTTPCheckInterval = 30 * time.Second
+	defaultHTTPCheckTimeout  = 5 * time.Second
+	httpCheckBodyLimit       = 1 << 20 // body_regex is matched against the first MiB
+)
+
+type httpCheck struct {
+	config models.HTTPCheckConfig
+	checkSchedule
+	body   *regexp.Regexp
+	client *http.Client
+
+	mu     sync.Mutex
+	result models.HTTPCheckResult
+}
+
+// httpCheckRunner runs every check in its own goroutine, collection only reads results
+type httpCheckRunner struct {
+	checks []*httpCheck
+}
+
+// newHTTPCheckRunner parses SYS_PULSE_HTTP_CHECKS, nil without checks
+func newHTTPCheckRunner(data string) (*httpCheckRunner, error) {
+	configs, err := parseCheckList[models.HTTPCheckConfig](data)
+	if err != nil || len(configs) == 0 {
+		return nil, err
+	}
+
+	hr := &httpCheckRunner{}
+	parser := newCheckParser("http check", checkSchedule{interval: defaultHTTPCheckInterval, timeout: defaultHTTPCheckTimeout})
+	for _, config := range configs {
+		schedule, err := parser.schedule(config.Name, config.Interval, config.Timeout)
+		if err != nil {
+			return nil, err
+		}
+		if u, err := url.Parse(config.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("http check %q: url must be an absolute http or https url", config.Name)
+		}
+		if config.Method == "" {
+			config.Method = http.MethodGet
+		}
+		for _, status := range config.ExpectedStatus {
+			if status < 100 || status > 599 {
+				return nil, fmt.Errorf("http check %q: expected status %d is not an http status", config.Name, status)
+			}
+		}
+
+		check := &httpCheck{config: config, checkSchedule: schedule}
+		if config.BodyRegex != "" {
+			if check.body, err = regexp.Compile(config.BodyRegex); err != nil {
+				return nil, fmt.Errorf("http check %q: body_regex: %w", config.Name, err)
+			}
+		}
+
+		// no connection reuse, every check pays for dns, connect and tls so the breakdown is meaningful
+		check.client = &http.Client{
+			Transport: &http.Transport{
+				Proxy:             http.ProxyFromEnvironment,
+				DisableKeepAlives: true,
+				TLSClientConfig:   &tls.Config{InsecureSkipVerify: config.InsecureSkipVerify},
+			},
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		}
+		check.result = models.HTTPCheckResult{Name: config.Name, URL: config.URL, Status: "unknown"}
+		hr.checks = append(hr.checks, check)
+	}
+	return hr, nil
+}
+
+// start runs checks until the process exits
+func (hr *httpCheckRunner) start() {
+	for _, check := range hr.checks {
+		runEvery(check.interval, check.update)
+	}
+}
+
+func (hc *httpCheck) update() {
+	result := hc.run()
+	hc.mu.Lock()
+	hc.result = result
+	hc.mu.Unlock()
+}
+
+// results returns checks in configured order, nil without checks
+func (hr *httpCheckRunner) results() []models.HTTPCheckResult {
+	if hr == nil || len(hr.checks) == 0 {
+		return nil
+	}
+	results := make([]models.HTTPCheckResult, 0, len(hr.checks))
+	for _, check := range hr.checks {
+		check.mu.Lock()
+		results = append(results, check.result)
+		check.mu.Unlock()
+	}
+	return results
+}
+
+func (hc *httpCheck) run() models.HTTPCheckResult {
+	result := models.HTTPCheckResult{Name: hc.config.Name, URL: hc.config.URL, Status: "down", LastCheck: time.Now()}
+	// trace callbacks can run in dialer goroutines, even after Do returned on timeout
+	var mu sync.Mutex
+	var start, dnsStart, dnsDone, connectStart, connectDone, tlsStart, tlsDone, firstByte time.Time
+	mark := func(t *time.Time) {
+		mu.Lock()
+		*t = time.Now()
+		mu.Unlock()
+	}
+	// phases that happened are filled for failed runs too, they tell where it failed
+	phases := func(end time.Time) {
+		mu.Lock()
+		defer mu.Unlock()
+		result.ResponseTime = millisSince(start, end)
+		result.DNS = millisSince(dnsStart, dnsDone)
+		result.Connect = millisSince(connectStart, connectDone)
+		result.TLS = millisSince(tlsStart, tlsDone)
+		result.TTFB = millisSince(start, firstByte)
+	}
+	fail := func(format string, args ...any) models.HTTPCheckResult {
+		result.LastError = fmt.Sprintf(format, args...)
+		return result
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), hc.timeout)
+	defer cancel()
+
+	trace := &httptrace.ClientTrace{
+		DNSStart:             func(httptrace.DNSStartInfo) { mark(&dnsStart) },
+		DNSDone:              func(httptrace.DNSDoneInfo) { mark(&dnsDone) },
+		ConnectStart:         func(string, string) { mark(&connectStart) },
+		ConnectDone:          func(string, string, error) { mark(&connectDone) },
+		TLSHandshakeStart:    func() { mark(&tlsStart) },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { mark(&tlsDone) },
+		GotFirstResponseByte: func() { mark(&firstByte) },
+	}
+
+	request, err := http.NewRequestWithContext(httptrace.WithClientTrace(ctx, trace), hc.config.Method, hc.config.URL, nil)
+	if err != nil {
+		return fail("%v", err)
+	}
+	for name, value := range hc.config.Headers {
+		if strings.EqualFold(name, "Host") {
+			request.Host = value
+		} else {
+			request.Header.Set(name, value)
+		}
+	}
+
+	mark(&start)
+	response, err := hc.client.Do(request)
+	if err != nil {
+		phases(time.Now())
+		return fail("%v", err)
+	}
+	defer response.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(response.Body, httpCheckBodyLimit))
+	phases(time.Now())
+
+	result.StatusCode = response.StatusCode
+	if response.TLS != nil && len(response.TLS.PeerCertificates) > 0 {
+		expiry := response.TLS.PeerCertificates[0].NotAfter
+		days := time.Until(expiry).Hours() / 24
+		result.CertExpiry = &expiry
+		result.CertExpiryDays = &days
+	}
+
+	if err != nil {
+		return fail("failed to read body: %v", err)
+	}
+	if len(hc.config.ExpectedStatus) > 0 {
+		if !slices.Contains(hc.config.ExpectedStatus, response.StatusCode) {
+			return fail("status %d, expected %v", response.StatusCode, hc.config.ExpectedStatus)
+		}
+	} else if response.StatusCode < 200 || response.StatusCode > 399 {
+		return fail("status %d", response.StatusCode)
+	}
+	if hc.body != nil && !hc.body.Match(body) {
+		return fail("body does not match %s", hc.config.BodyRegex)
+	}
+
+	result.Status = "up"
+	return result
+}
+
+// millisSince returns 0 when a phase did not happen, e.g. no dns for an ip address or no tls for http
+func millisSince(start, end time.Time) float64 {
+	if start.IsZero() || end.IsZero() {
+		return 0
+	}
+	return float64(end.Sub(start)) / float64(time.Millisecond)
+}
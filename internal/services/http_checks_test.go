@@ -0,0 +1,195 @@
+package services
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"syspulse/internal/models"
+	"testing"
+	"time"
+)
+
+// runHTTPCheck builds a check from config the way SYS_PULSE_HTTP_CHECKS does and runs it once
+func runHTTPCheck(t *testing.T, config models.HTTPCheckConfig) models.HTTPCheckResult {
+	t.Helper()
+	if config.Name == "" {
+		config.Name = "test"
+	}
+	data, err := json.Marshal([]models.HTTPCheckConfig{config})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hr, err := newHTTPCheckRunner(string(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hr.checks[0].run()
+}
+
+func TestHTTPCheckStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/down" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	result := runHTTPCheck(t, models.HTTPCheckConfig{URL: server.URL + "/ok"})
+	if result.Status != "up" || result.StatusCode != 200 || result.LastError != "" {
+		t.Errorf("ok: %s %d %q, want up 200", result.Status, result.StatusCode, result.LastError)
+	}
+	if result.ResponseTime <= 0 || result.TTFB <= 0 || result.Connect <= 0 {
+		t.Errorf("ok: response %.3f, ttfb %.3f, connect %.3f, want all positive", result.ResponseTime, result.TTFB, result.Connect)
+	}
+
+	result = runHTTPCheck(t, models.HTTPCheckConfig{URL: server.URL + "/down"})
+	if result.Status != "down" || result.StatusCode != 503 || result.LastError != "status 503" {
+		t.Errorf("default status: %s %d %q, want down 503", result.Status, result.StatusCode, result.LastError)
+	}
+
+	result = runHTTPCheck(t, models.HTTPCheckConfig{URL: server.URL + "/ok", ExpectedStatus: []int{204}})
+	if result.Status != "down" || !strings.Contains(result.LastError, "expected [204]") {
+		t.Errorf("expected status mismatch: %s %q, want down", result.Status, result.LastError)
+	}
+
+	result = runHTTPCheck(t, models.HTTPCheckConfig{URL: server.URL + "/down", ExpectedStatus: []int{503}})
+	if result.Status != "up" {
+		t.Errorf("expected 503: %s %q, want up", result.Status, result.LastError)
+	}
+}
+
+func TestHTTPCheckBodyRegex(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"status":"degraded"}`))
+	}))
+	defer server.Close()
+
+	result := runHTTPCheck(t, models.HTTPCheckConfig{URL: server.URL, BodyRegex: `"status":"(ok|degraded)"`})
+	if result.Status != "up" {
+		t.Errorf("matching body: %s %q, want up", result.Status, result.LastError)
+	}
+
+	result = runHTTPCheck(t, models.HTTPCheckConfig{URL: server.URL, BodyRegex: `"status":"ok"`})
+	if result.Status != "down" || !strings.Contains(result.LastError, "body does not match") || result.StatusCode != 200 {
+		t.Errorf("body mismatch: %s %d %q, want down 200", result.Status, result.StatusCode, result.LastError)
+	}
+}
+
+func TestHTTPCheckHeaders(t *testing.T) {
+	hosts := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hosts <- r.Host + " " + r.Header.Get("X-Check") + " " + r.Method
+	}))
+	defer server.Close()
+
+	result := runHTTPCheck(t, models.HTTPCheckConfig{
+		URL:     server.URL,
+		Method:  http.MethodHead,
+		Headers: map[string]string{"host": "app.example.test", "X-Check": "syspulse"},
+	})
+	if result.Status != "up" {
+		t.Fatalf("check failed: %q", result.LastError)
+	}
+	if got, want := <-hosts, "app.example.test syspulse HEAD"; got != want {
+		t.Errorf("server got %q, want %q", got, want)
+	}
+}
+
+func TestHTTPCheckDoesNotFollowRedirects(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		http.Redirect(w, r, "/login", http.StatusFound)
+	}))
+	defer server.Close()
+
+	result := runHTTPCheck(t, models.HTTPCheckConfig{URL: server.URL + "/app"})
+	if result.Status != "up" || result.StatusCode != http.StatusFound {
+		t.Errorf("redirect: %s %d %q, want up 302", result.Status, result.StatusCode, result.LastError)
+	}
+
+	result = runHTTPCheck(t, models.HTTPCheckConfig{URL: server.URL + "/app", ExpectedStatus: []int{200}})
+	if result.Status != "down" || result.StatusCode != http.StatusFound {
+		t.Errorf("redirect with expected 200: %s %d, want down 302", result.Status, result.StatusCode)
+	}
+}
+
+func TestHTTPCheckTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	start := time.Now()
+	result := runHTTPCheck(t, models.HTTPCheckConfig{URL: server.URL, Timeout: "200ms"})
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("check took %v with 200ms timeout", elapsed)
+	}
+	if result.Status != "down" || !strings.Contains(result.LastError, "deadline exceeded") {
+		t.Errorf("timeout: %s %q, want down with deadline exceeded", result.Status, result.LastError)
+	}
+	if result.Connect <= 0 || result.TTFB != 0 {
+		t.Errorf("timeout: connect %.3f, ttfb %.3f, want connect only", result.Connect, result.TTFB)
+	}
+}
+
+func TestHTTPCheckCertExpiry(t *testing.T) {
+	handler := http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})
+	tlsServer := httptest.NewUnstartedServer(handler)
+	tlsServer.Config.ErrorLog = log.New(io.Discard, "", 0) // the rejected handshake is expected
+	tlsServer.StartTLS()
+	defer tlsServer.Close()
+	plainServer := httptest.NewServer(handler)
+	defer plainServer.Close()
+
+	// httptest certificate is self-signed, a verified check must fail on it
+	result := runHTTPCheck(t, models.HTTPCheckConfig{URL: tlsServer.URL})
+	if result.Status != "down" || !strings.Contains(result.LastError, "certificate") {
+		t.Errorf("untrusted certificate: %s %q, want down", result.Status, result.LastError)
+	}
+
+	result = runHTTPCheck(t, models.HTTPCheckConfig{URL: tlsServer.URL, InsecureSkipVerify: true})
+	if result.Status != "up" || result.CertExpiry == nil || result.CertExpiryDays == nil {
+		t.Fatalf("https: %s %q, expiry %v, want up with expiry", result.Status, result.LastError, result.CertExpiry)
+	}
+	wantDays := time.Until(tlsServer.Certificate().NotAfter).Hours() / 24
+	assertNear(t, "cert expiry days", *result.CertExpiryDays, wantDays, 0.01)
+	if result.TLS <= 0 {
+		t.Errorf("https: tls %.3f, want positive", result.TLS)
+	}
+
+	result = runHTTPCheck(t, models.HTTPCheckConfig{URL: plainServer.URL})
+	if result.Status != "up" || result.CertExpiry != nil || result.CertExpiryDays != nil || result.TLS != 0 {
+		t.Errorf("http: %s, expiry %v, tls %.3f, want up without certificate", result.Status, result.CertExpiry, result.TLS)
+	}
+}
+
+func TestHTTPCheckConfigValidation(t *testing.T) {
+	for _, tc := range []struct {
+		config  string
+		wantErr string
+	}{
+		{`[{"url":"http://a"}]`, "http check needs a name"},
+		{`[{"name":"a","url":"http://a"},{"name":"a","url":"http://b"}]`, `duplicate http check "a"`},
+		{`[{"name":"a","url":"ftp://a"}]`, "absolute http or https url"},
+		{`[{"name":"a","url":"/health"}]`, "absolute http or https url"},
+		{`[{"name":"a","url":"http://a","expected_status":[42]}]`, "not an http status"},
+		{`[{"name":"a","url":"http://a","interval":"100ms"}]`, "at least 1s, e.g. 30s"},
+		{`[{"name":"a","url":"http://a","interval":"10s","timeout":"20s"}]`, "not longer than interval"},
+		{`[{"name":"a","url":"http://a","body_regex":"("}]`, "body_regex"},
+	} {
+		if _, err := newHTTPCheckRunner(tc.config); err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+			t.Errorf("newHTTPCheckRunner(%s) err = %v, want %q", tc.config, err, tc.wantErr)
+		}
+	}
+}
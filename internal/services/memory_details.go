@@ -0,0 +1,42 @@
+package services
+
+import (
+	"path/filepath"
+	"syspulse/internal/models"
+	"time"
+)
+
+// vmstatCounters keeps /proc/vmstat counters of previous collection for rates
+type vmstatCounters struct {
+	procRoot string
+	pageSize uint64
+	prev     map[string]uint64
+	prevTime time.Time
+}
+
+// update reads /proc/vmstat and fills fault and swap rates and oom kills, on systems without procfs it does nothing
+func (vc *vmstatCounters) update(memory *models.MemInfo) {
+	current, err := readKeyValueFile(filepath.Join(vc.procRoot, "vmstat"))
+	if err != nil {
+		return
+	}
+	now := time.Now()
+	memory.OOMKills = current["oom_kill"]
+
+	if vc.prev != nil {
+		if elapsed := now.Sub(vc.prevTime).Seconds(); elapsed > 0 {
+			rate := func(key string) float64 {
+				return float64(counterDelta(vc.prev[key], current[key])) / elapsed
+			}
+			// pgfault counts all faults, major ones included
+			memory.MajorFaultRate = rate("pgmajfault")
+			memory.MinorFaultRate = max(rate("pgfault")-memory.MajorFaultRate, 0)
+			memory.Swap.InRate = rate("pswpin") * float64(vc.pageSize)
+			memory.Swap.OutRate = rate("pswpout") * float64(vc.pageSize)
+		}
+		memory.NewOOMKills = counterDelta(vc.prev["oom_kill"], current["oom_kill"])
+	}
+
+	vc.prev = current
+	vc.prevTime = now
+}
@@ -2,65 +2,172 @@ package services
 
 import (
 	"fmt"
-	"io"
 	"log"
-	"net/http"
 	"os"
-	"runtime"
+	"sync"
+	"syspulse/internal/config"
 	"syspulse/internal/models"
 	"time"
 
 	"net"
 
 	"github.com/shirou/gopsutil/v3/cpu"
-	"github.com/shirou/gopsutil/v3/disk"
 	"github.com/shirou/gopsutil/v3/load"
 	"github.com/shirou/gopsutil/v3/mem"
-	gnet "github.com/shirou/gopsutil/v3/net"
-	"github.com/shirou/gopsutil/v3/process"
 )
 
 type MetricsService struct {
-	lastCPUStats       *CPUStats
-	prevNetCounters    map[string]gnet.IOCountersStat // contains value of prev net counters
-	prevNetTime        time.Time                      //time of prev time measure
-	pingServers        []string                       // primary servers for measuring ping
-	currentServerIndex int
-	totalUpload        uint64
-	totalDownload      uint64
+	collectMu    sync.Mutex            // only one collection at a time, collectors keep state between runs
+	snapshotMu   sync.RWMutex          // guards latest
+	latest       *models.SystemMetrics // last collected metrics, served to api clients
+	lastCPUStats *CPUStats             // cpu counters from previous collection, usage is the delta to them
+	cpuDetails   *cpuDetails           // reads cpu counters and computes usage
+	probes       *probeRunner          // latency probes, nil without SYS_PULSE_PROBES
+	httpChecks   *httpCheckRunner      // nil without SYS_PULSE_HTTP_CHECKS
+	publicIP     *publicIPResolver     // nil when public ip lookup is disabled
+	systemInfo   *systemInfoCollector
+	processes    *processCache  // processes from previous collection, for cpu deltas
+	groups       *processGroups // watched process groups
+	pressure     *pressureCollector
+	cgroups      *cgroupCollector
+	ownLimits    bool           // top level cpu and memory are replaced with those of the own cgroup
+	vmstat       vmstatCounters // for page fault and swap rates
+	filesystems  *filesystemCollector
+	diskIO       *diskIOCollector
+	interfaces   *interfaceCollector
+	sockets      *socketCollector
+	sensors      *sensorCollector
+	docker       *dockerCollector // nil without SYS_PULSE_DOCKER_SOCKET
+	ioSamples    ioSampleStore    // io counters from previous process details requests
 }
 
-func NewMetricsService() *MetricsService {
+func NewMetricsService(cfg *config.Config) *MetricsService {
+	cores, _ := cpu.Counts(true)
+
+	var groups *processGroups
+	if configs, err := ParseProcessGroups(cfg.ProcessGroups); err != nil {
+		log.Printf("❌ SYS_PULSE_PROCESS_GROUPS is ignored: %v", err)
+	} else {
+		groups, _ = newProcessGroups(configs)
+	}
+
+	probes, err := newProbeRunner(cfg.Probes)
+	if err != nil {
+		log.Printf("❌ SYS_PULSE_PROBES is ignored: %v", err)
+	} else if probes != nil {
+		probes.start()
+		log.Printf("📡 %d latency probes started", len(probes.probes))
+	}
+
+	httpChecks, err := newHTTPCheckRunner(cfg.HTTPChecks)
+	if err != nil {
+		log.Printf("❌ SYS_PULSE_HTTP_CHECKS is ignored: %v", err)
+	} else if httpChecks != nil {
+		httpChecks.start()
+		log.Printf("📡 %d http checks started", len(httpChecks.checks))
+	}
+
+	publicIP, err := newPublicIPResolver(cfg.PublicIPProviders, time.Duration(cfg.PublicIPInterval)*time.Second)
+	if err != nil {
+		log.Printf("❌ SYS_PULSE_PUBLIC_IP is ignored: %v", err)
+	}
+
+	cgroups := newCgroupCollector(cfg.SysRoot, cfg.Cgroups)
+	if cfg.ContainerLimits && cgroups.self == "" {
+		log.Printf("❌ SYS_PULSE_CONTAINER_LIMITS is ignored: own cgroup v2 is not known")
+	}
+
+	docker := newDockerCollector(cfg.DockerSocket, cfg.ProcRoot, cgroups)
+	if docker != nil {
+		docker.start()
+		log.Printf("🐳 watching docker containers at %s", cfg.DockerSocket)
+	}
+
+	cpuDetails := newCPUDetails(cfg.ProcRoot, cfg.SysRoot)
+	lastCPUStats, _ := cpuDetails.source() // so the first collection already has something to compare with
+
 	return &MetricsService{
-		prevNetCounters:    make(map[string]gnet.IOCountersStat),
-		prevNetTime:        time.Now(),
-		pingServers:        []string{"8.8.8.8", "1.1.1.1", "77.88.8.8"},
-		currentServerIndex: 0,
+		cpuDetails:   cpuDetails,
+		lastCPUStats: lastCPUStats,
+		groups:       groups,
+		pressure:     newPressureCollector(cfg.ProcRoot, cfg.SysRoot, cfg.PSICgroups),
+		cgroups:      cgroups,
+		ownLimits:    cfg.ContainerLimits,
+		vmstat:       vmstatCounters{procRoot: cfg.ProcRoot, pageSize: uint64(os.Getpagesize())},
+		filesystems:  newFilesystemCollector(cfg.ProcRoot, cfg.DiskInclude, cfg.DiskExclude),
+		diskIO:       newDiskIOCollector(),
+		interfaces:   newInterfaceCollector(cfg.SysRoot, cfg.NetInclude, cfg.NetExclude, cfg.StateDir),
+		sockets:      newSocketCollector(cfg.ProcRoot),
+		sensors:      newSensorCollector(cfg.SysRoot),
+		docker:       docker,
+		processes:    newProcessCache(cfg.ProcRoot, cfg.NormalizeCPU, cores),
+		ioSamples:    ioSampleStore{samples: make(map[int32]ioSample)},
+		probes:       probes,
+		httpChecks:   httpChecks,
+		publicIP:     publicIP,
+		systemInfo:   newSystemInfoCollector(cfg.ProcRoot, cpuDetails.modelName),
 	}
 }
 
+// GetSystemMetrics runs full collection and remembers result as latest snapshot
 func (ms *MetricsService) GetSystemMetrics() models.SystemMetrics {
-	return models.SystemMetrics{
+	ms.collectMu.Lock()
+	defer ms.collectMu.Unlock()
+
+	memory := ms.getMemoryInfo()
+	interfaces := ms.interfaces.collect()
+	probes := ms.probes.results()
+	metrics := models.SystemMetrics{
 		TimeStamp:      time.Now(),
 		CPU:            ms.getCPUInfo(),
-		Memory:         ms.getMemoryInfo(),
-		Disk:           ms.getDiskInfo(),
-		System:         ms.getSystemInfo(),
-		Network:        ms.getNetworkMetrics(),
-		Processes:      ms.getProcessMetrics(),
-		NetworkDetails: ms.getNetworkDetailsMetrics(),
-	}
+		Memory:         memory,
+		System:         ms.systemInfo.collect(),
+		Network:        ms.getNetworkMetrics(interfaces, probes),
+		NetworkDetails: ms.getNetworkDetailsMetrics(interfaces),
+		Interfaces:     interfaces,
+		Probes:         probes,
+		HTTPChecks:     ms.httpChecks.results(),
+		Pressure:       ms.pressure.collect(),
+		Sensors:        ms.sensors.collect(),
+		Cgroups:        ms.cgroups.collect(),
+	}
+	if ms.ownLimits && metrics.Cgroups != nil && metrics.Cgroups.Self != nil {
+		applyContainerLimits(&metrics.CPU, &metrics.Memory, metrics.Cgroups.Self)
+	}
+	metrics.Filesystems, metrics.Disk = ms.filesystems.collect()
+	metrics.DiskIO = ms.diskIO.collect(metrics.Filesystems)
+	metrics.Processes, metrics.Events, metrics.ProcessStats = ms.processes.collect(memory.Total)
+	metrics.Sockets = ms.sockets.collect(metrics.Processes)
+
+	groups, groupEvents := ms.groups.update(metrics.Processes, metrics.Events, metrics.TimeStamp)
+	metrics.ProcessGroups = groups
+	metrics.Events = append(metrics.Events, groupEvents...)
+
+	containers, containerEvents := ms.docker.collect()
+	metrics.Containers = containers
+	metrics.Events = append(metrics.Events, containerEvents...)
+
+	ms.snapshotMu.Lock()
+	ms.latest = &metrics
+	ms.snapshotMu.Unlock()
+
+	return metrics
 }
 
-func (ms *MetricsService) getCPUInfo() models.CPUInfo {
+// GetLatestMetrics returns last snapshot without collecting, collects only if there is no snapshot yet
+func (ms *MetricsService) GetLatestMetrics() models.SystemMetrics {
+	ms.snapshotMu.RLock()
+	latest := ms.latest
+	ms.snapshotMu.RUnlock()
 
-	cpuPercent, _ := cpu.Percent(400*time.Millisecond, false)
-	cpuUsage := 0.0
-
-	if len(cpuPercent) > 0 {
-		cpuUsage = cpuPercent[0]
+	if latest == nil {
+		return ms.GetSystemMetrics()
 	}
+	return *latest
+}
 
+// getCPUInfo computes usage from cpu counters between this and previous collection, so it never waits
+func (ms *MetricsService) getCPUInfo() models.CPUInfo {
 	cpuCores, _ := cpu.Counts(true)
 
 	avgLoad, _ := load.Avg()
@@ -71,77 +178,71 @@ func (ms *MetricsService) getCPUInfo() models.CPUInfo {
 		load15 = avgLoad.Load15
 	}
 
+	var usage cpuUsage
+	if current, err := ms.cpuDetails.source(); err == nil {
+		var rebase bool
+		if usage, rebase = ms.cpuDetails.compute(ms.lastCPUStats, current); rebase {
+			ms.lastCPUStats = current
+		}
+	}
+
 	return models.CPUInfo{
-		Usage:  cpuUsage,
-		Cores:  cpuCores,
-		Load1:  load1,
-		Load5:  load5,
-		Load15: load15,
+		Usage:         usage.usage,
+		Cores:         cpuCores,
+		PhysicalCores: ms.cpuDetails.physical,
+		ModelName:     ms.cpuDetails.modelName,
+		Load1:         load1,
+		Load5:         load5,
+		Load15:        load15,
+		Times:         usage.times,
+		PerCore:       usage.perCore,
 	}
 }
 
+// getMemoryInfo reports system memory, when it can't be read Error is set instead of numbers
 func (ms *MetricsService) getMemoryInfo() models.MemInfo {
-
-	var memStats runtime.MemStats
-	runtime.ReadMemStats(&memStats)
 	memory, err := mem.VirtualMemory()
 	if err != nil {
-		var memStat runtime.MemStats
-		runtime.ReadMemStats(&memStat)
-		return models.MemInfo{
-			Total:     memStat.Sys,
-			Used:      memStat.Alloc,
-			Available: memStat.Sys - memStat.Alloc,
-			Usage:     float64(memStats.Alloc) / float64(memStats.Sys) * 100,
+		return models.MemInfo{Error: fmt.Sprintf("failed to read memory: %v", err)}
+	}
+
+	info := models.MemInfo{
+		Total:             memory.Total,
+		Used:              memory.Used,
+		Available:         memory.Available,
+		Usage:             memory.UsedPercent,
+		Free:              memory.Free,
+		Buffers:           memory.Buffers,
+		Cached:            memory.Cached,
+		Shared:            memory.Shared,
+		SlabReclaimable:   memory.Sreclaimable,
+		SlabUnreclaimable: memory.Sunreclaim,
+		Dirty:             memory.Dirty,
+		Writeback:         memory.WriteBack,
+	}
+	if memory.HugePagesTotal > 0 {
+		info.HugePages = &models.HugePages{
+			Total:    memory.HugePagesTotal,
+			Free:     memory.HugePagesFree,
+			Reserved: memory.HugePagesRsvd,
+			Surplus:  memory.HugePagesSurp,
+			PageSize: memory.HugePageSize,
 		}
 	}
-	return models.MemInfo{
-		Total:     memory.Total,
-		Used:      memory.Used,
-		Available: memory.Available,
-		Usage:     memory.UsedPercent,
-	}
-}
-
-func (ms *MetricsService) getDiskInfo() models.DiskInfo {
-
-	partitions, err := disk.Partitions(false)
-	if err != nil {
-		return ms.getDiskInfoFallback()
-
-	}
-
-	var largestPartition string
-	var maxsize uint64
 
-	for _, partition := range partitions {
-		if isSpecialFilesystem(partition.Fstype) {
-			continue
-		}
-
-		usage, err := disk.Usage(partition.Mountpoint)
-		if err != nil {
-			continue
-		}
-
-		if usage.Total > maxsize {
-			maxsize = usage.Total
-			largestPartition = partition.Mountpoint
+	if swap, err := mem.SwapMemory(); err == nil {
+		info.Swap = models.SwapInfo{
+			Total: swap.Total,
+			Used:  swap.Used,
+			Free:  swap.Free,
+			Usage: swap.UsedPercent,
 		}
+	} else {
+		info.Swap.Error = fmt.Sprintf("failed to read swap: %v", err)
 	}
 
-	if largestPartition != "" {
-		usage, err := disk.Usage(largestPartition)
-		if err == nil {
-			return models.DiskInfo{
-				Total: usage.Total,
-				Used:  usage.Used,
-				Free:  usage.Free,
-				Usage: usage.UsedPercent,
-			}
-		}
-	}
-	return ms.getDiskInfoFallback()
+	ms.vmstat.update(&info)
+	return info
 }
 
 func isSpecialFilesystem(fstype string) bool {
@@ -149,6 +250,8 @@ func isSpecialFilesystem(fstype string) bool {
 		"tmpfs", "devtmpfs", "squashfs", "overlay",
 		"proc", "sysfs", "devpts", "mqueue", "debugfs",
 		"securityfs", "pstore", "cgroup", "cgroup2",
+		"autofs", "binfmt_misc", "bpf", "configfs", "efivarfs", "fusectl",
+		"hugetlbfs", "nsfs", "ramfs", "rpc_pipefs", "selinuxfs", "tracefs",
 	}
 
 	for _, fs := range specialFS {
@@ -159,127 +262,35 @@ func isSpecialFilesystem(fstype string) bool {
 	return false
 }
 
-func (ms *MetricsService) getDiskInfoFallback() models.DiskInfo {
-
-	mountPoints := []string{"/", "/home", "/mnt", "/media", "C:\\", "D:\\"}
-
-	for _, point := range mountPoints {
-		if usage, err := disk.Usage(point); err == nil {
-			return models.DiskInfo{
-				Total: usage.Total,
-				Used:  usage.Used,
-				Free:  usage.Free,
-				Usage: usage.UsedPercent,
-			}
-		}
-	}
-
-	return models.DiskInfo{
-		Total: 5000 * 1024 * 1024 * 1024,
-		Used:  1234 * 1024 * 1024 * 1024,
-		Free:  1540 * 1024 * 1024 * 1024,
-		Usage: 35.0,
-	}
-}
-
-func (ms *MetricsService) getSystemInfo() models.SystemInfo {
-
-	hostname, _ := os.Hostname()
-	return models.SystemInfo{
-		Hostname: hostname,
-		OS:       runtime.GOOS,
-		Platform: runtime.GOARCH,
-		Uptime:   uint64(time.Now().Unix() - ms.getSystemUpTime()),
-	}
-}
-
-func (ms *MetricsService) getSystemUpTime() int64 {
-	return time.Now().Add(-24 * time.Hour).Unix()
-}
-
-type CPUStats struct {
-	Time  time.Time
-	Usage float64
-}
-
 // ─── Network Stats ─────────────────────────────────────────────────────────
 
-func (ms *MetricsService) calculateNetworkSpeed() (float64, float64, uint64, uint64) {
-	currentCounters, err := gnet.IOCounters(true)
-	if err != nil {
-		return 0, 0, ms.totalUpload, ms.totalDownload
-	}
+// getNetworkMetrics sums rates of reported interfaces, loopback is excluded by default so local traffic is not counted.
+// Online state comes from probes, nothing is sent anywhere unless probes are configured
+func (ms *MetricsService) getNetworkMetrics(interfaces []models.NetworkInterface, probes []models.ProbeResult) models.NetworkStats {
+	stats := models.NetworkStats{}
 
-	var curBytesSent, curBytesRecv uint64
-	for _, counter := range currentCounters {
-		curBytesRecv += counter.BytesRecv
-		curBytesSent += counter.BytesSent
+	for _, iface := range interfaces {
+		stats.CurrentUpload += iface.TxRate * 8 / 1000000   // Mb/s
+		stats.CurrentDownload += iface.RxRate * 8 / 1000000 // Mb/s
 	}
 
-	timeDelta := time.Since(ms.prevNetTime).Seconds()
-	var uploadSpeed, downloadSpeed float64
-
-	if len(ms.prevNetCounters) > 0 {
-		var prevBytesRecv, prevBytesSent uint64
-		for _, counter := range ms.prevNetCounters {
-			prevBytesRecv += counter.BytesRecv
-			prevBytesSent += counter.BytesSent
+	if len(probes) > 0 {
+		for _, probe := range probes {
+			if probe.Status == "up" {
+				stats.IsOnline = true
+				stats.Ping = probe.Latency
+				break
+			}
 		}
-		uploadSpeed = float64(curBytesSent-prevBytesSent) * 8 / timeDelta / 1000000   // Mb/s
-		downloadSpeed = float64(curBytesRecv-prevBytesRecv) * 8 / timeDelta / 1000000 // Mb/s
-
-		if curBytesSent > prevBytesSent {
-			ms.totalUpload += (curBytesSent - prevBytesSent) / 1024 / 1024
-			ms.totalDownload += (curBytesRecv - prevBytesRecv) / 1024 / 1024
+	} else {
+		for _, iface := range interfaces {
+			if iface.OperState == "up" && len(iface.Addresses) > 0 {
+				stats.IsOnline = true
+				break
+			}
 		}
 	}
 
-	ms.prevNetCounters = make(map[string]gnet.IOCountersStat)
-	for _, counter := range currentCounters {
-		ms.prevNetCounters[counter.Name] = counter
-	}
-	ms.prevNetTime = time.Now()
-
-	return uploadSpeed, downloadSpeed, ms.totalUpload, ms.totalDownload
-}
-
-func (ms *MetricsService) measurePing() float64 {
-	server := ms.pingServers[ms.currentServerIndex]
-
-	pingTime, err := ms.tcpPing(server)
-	if err != nil {
-		ms.currentServerIndex = (ms.currentServerIndex + 1) % len(ms.pingServers)
-		return 1000.0
-	}
-
-	return pingTime
-}
-
-func (ms *MetricsService) tcpPing(server string) (float64, error) {
-	start := time.Now()
-
-	conn, err := net.DialTimeout("tcp", server+":80", 3*time.Second)
-	if err != nil {
-		return 0, fmt.Errorf("failed to connect ping server: %w", err)
-	}
-	defer conn.Close()
-
-	ping := time.Since(start).Seconds() * 1000 // ping in ms
-
-	return ping, nil
-}
-
-func (ms *MetricsService) getNetworkMetrics() models.NetworkStats {
-	stats := models.NetworkStats{}
-
-	upload, download, _, _ := ms.calculateNetworkSpeed()
-	stats.CurrentUpload = upload
-	stats.CurrentDownload = download
-
-	stats.Ping = ms.measurePing()
-
-	stats.IsOnline = stats.Ping <= 1000
-
 	stats.LocalIP = ms.getLocalIP()
 
 	return stats
@@ -297,16 +308,26 @@ func (ms *MetricsService) getLocalIP() string {
 	return localAddr.IP.String()
 }
 
+// NetworkTraffic returns traffic accounting of reported interfaces
+func (ms *MetricsService) NetworkTraffic() models.NetworkTraffic {
+	return ms.interfaces.traffic.report()
+}
+
 // ─── Network Details ─────────────────────────────────────────────────────────
 
-func (ms *MetricsService) getNetworkDetailsMetrics() *models.NetworkDetails {
-	_, _, totalUpload, totalDownload := ms.calculateNetworkSpeed()
+func (ms *MetricsService) getNetworkDetailsMetrics(interfaces []models.NetworkInterface) *models.NetworkDetails {
+	names := make([]string, 0, len(interfaces))
+	for _, iface := range interfaces {
+		names = append(names, iface.Name)
+	}
+	totalDownload, totalUpload := ms.interfaces.traffic.totals(names)
+	publicIP, publicIPError := ms.publicIP.get()
 	details := models.NetworkDetails{
-		PublicIP:      getPublicIP(),
+		PublicIP:      publicIP,
 		MACAddress:    getMacAddr(),
 		TotalUpload:   totalUpload,
 		TotalDownload: totalDownload,
-		ErrorMessage:  "",
+		ErrorMessage:  publicIPError,
 	}
 
 	return &details
@@ -325,102 +346,3 @@ func getMacAddr() string {
 
 	return ""
 }
-
-func getPublicIP() string {
-	resp, err := http.Get("https://api.ipify.org")
-	if err != nil {
-		log.Printf("failed to get public IP from API: %v", err)
-		return ""
-	}
-	defer resp.Body.Close()
-
-	ip, err := io.ReadAll(resp.Body)
-	if err != nil {
-		log.Printf("failed to read public IP from API: %v", err)
-		return ""
-	}
-
-	return string(ip)
-}
-
-// ─── Process Metrics ─────────────────────────────────────────────────────────
-
-func (ms *MetricsService) getProcessMetrics() []models.ProcessInfo {
-	processes, err := process.Processes()
-	if err != nil {
-		return []models.ProcessInfo{}
-	}
-
-	var processMetrics []models.ProcessInfo
-	for _, process := range processes {
-		if info, err := ms.getSingleProcesInfo(process); err == nil {
-			processMetrics = append(processMetrics, info)
-		}
-	}
-
-	return processMetrics
-}
-
-func (ms *MetricsService) getSingleProcesInfo(p *process.Process) (models.ProcessInfo, error) {
-
-	status, err := p.Status()
-	if err != nil {
-		return models.ProcessInfo{}, err
-	}
-
-	if status[0] == "Z" {
-		return models.ProcessInfo{}, fmt.Errorf("zomvie process")
-	}
-
-	info := models.ProcessInfo{PID: p.Pid}
-
-	process, err := p.Name()
-	if err == nil {
-		info.Process = process
-	}
-	cpuPercent, err := p.CPUPercent()
-	if err == nil {
-		info.CPUPercent = cpuPercent
-	}
-	memPercent, err := p.MemoryPercent()
-	if err == nil {
-		info.MemoryPercent = float64(memPercent)
-	}
-	memRSS, err := p.MemoryInfo()
-	if err == nil {
-		info.MemoryRSS = memRSS.RSS
-	}
-	info.Status = status[0]
-
-	commandLine, err := p.Cmdline()
-	if err == nil {
-		if commandLine == "" {
-			info.CommandLine = "System process"
-		} else {
-			info.CommandLine = commandLine
-		}
-	} else {
-		info.CommandLine = "N/A"
-	}
-
-	user, err := p.Username()
-	if err == nil {
-		info.User = user
-	}
-
-	threads, err := p.NumThreads()
-	if err == nil {
-		info.Threads = int(threads)
-	} else {
-		info.Threads = -1
-	}
-
-	createTime, err := p.CreateTime()
-	if err == nil {
-		info.CreateTime = createTime / 1000
-	} else {
-		info.CreateTime = 0
-	}
-
-	return info, nil
-}
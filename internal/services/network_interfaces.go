@@ -0,0 +1,188 @@
+package services
+
+import (
+	"math"
+	"net"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syspulse/internal/models"
+	"time"
+
+	gnet "github.com/shirou/gopsutil/v3/net"
+)
+
+// interfaceSample is counters of one interface from previous collection
+type interfaceSample struct {
+	counters gnet.IOCountersStat
+	index    int  // a new index means the interface was recreated and counters started from zero
+	wraps    bool // a counter that went back since previous collection wrapped at 2^32, see countersReset
+}
+
+// interfaceCollector reports counters, rates and link info of network interfaces filtered by include and exclude globs
+type interfaceCollector struct {
+	sysRoot  string
+	include  []string
+	exclude  []string
+	prev     map[string]interfaceSample
+	prevTime time.Time
+	traffic  *trafficAccounting // totals of reported interfaces, fed from the same sample as rates
+}
+
+func newInterfaceCollector(sysRoot, include, exclude, stateDir string) *interfaceCollector {
+	return &interfaceCollector{
+		sysRoot: sysRoot,
+		include: splitPatterns(include),
+		exclude: splitPatterns(exclude),
+		prev:    make(map[string]interfaceSample),
+		traffic: newTrafficAccounting(stateDir),
+	}
+}
+
+// collect samples counters of all interfaces once, rates are deltas to previous collection,
+// traffic accounting gets the same sample
+func (ic *interfaceCollector) collect() []models.NetworkInterface {
+	counters, err := gnet.IOCounters(true)
+	if err != nil {
+		return nil
+	}
+	now := time.Now()
+	elapsed := now.Sub(ic.prevTime).Seconds()
+
+	links := make(map[string]net.Interface)
+	if interfaces, err := net.Interfaces(); err == nil {
+		for _, iface := range interfaces {
+			links[iface.Name] = iface
+		}
+	}
+
+	current := make(map[string]interfaceSample, len(counters))
+	reported := make(map[string]interfaceSample, len(counters))
+	result := make([]models.NetworkInterface, 0, len(counters))
+	for _, counter := range counters {
+		link, hasLink := links[counter.Name]
+		sample := interfaceSample{counters: counter, index: link.Index}
+		if prev, ok := ic.prev[counter.Name]; ok && prev.index == sample.index {
+			sample.wraps = !countersReset(prev.counters, counter)
+		}
+		current[counter.Name] = sample
+		if !ic.wanted(counter.Name) {
+			continue
+		}
+		reported[counter.Name] = sample
+
+		iface := models.NetworkInterface{
+			Name:      counter.Name,
+			Addresses: []string{},
+			Duplex:    "unknown",
+			OperState: "unknown",
+			RxBytes:   counter.BytesRecv,
+			TxBytes:   counter.BytesSent,
+			RxPackets: counter.PacketsRecv,
+			TxPackets: counter.PacketsSent,
+			RxErrors:  counter.Errin,
+			TxErrors:  counter.Errout,
+			RxDropped: counter.Dropin,
+			TxDropped: counter.Dropout,
+		}
+		if hasLink {
+			iface.MACAddress = link.HardwareAddr.String()
+			iface.MTU = link.MTU
+			if link.Flags&net.FlagUp != 0 {
+				iface.OperState = "up"
+			} else {
+				iface.OperState = "down"
+			}
+			if addrs, err := link.Addrs(); err == nil {
+				for _, addr := range addrs {
+					iface.Addresses = append(iface.Addresses, addr.String())
+				}
+			}
+		}
+		ic.readLinkInfo(&iface)
+
+		if prev, ok := ic.prev[counter.Name]; ok && elapsed > 0 && prev.index == sample.index {
+			rate := func(prev, current uint64) float64 {
+				return float64(interfaceCounterDelta(prev, current, sample.wraps)) / elapsed
+			}
+			iface.RxRate = rate(prev.counters.BytesRecv, counter.BytesRecv)
+			iface.TxRate = rate(prev.counters.BytesSent, counter.BytesSent)
+			iface.RxPacketRate = rate(prev.counters.PacketsRecv, counter.PacketsRecv)
+			iface.TxPacketRate = rate(prev.counters.PacketsSent, counter.PacketsSent)
+			iface.RxErrorRate = rate(prev.counters.Errin, counter.Errin)
+			iface.TxErrorRate = rate(prev.counters.Errout, counter.Errout)
+			iface.RxDropRate = rate(prev.counters.Dropin, counter.Dropin)
+			iface.TxDropRate = rate(prev.counters.Dropout, counter.Dropout)
+		}
+		result = append(result, iface)
+	}
+
+	ic.prev = current
+	ic.prevTime = now
+	ic.traffic.account(reported, now)
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+func (ic *interfaceCollector) wanted(name string) bool {
+	matches := func(patterns []string) bool {
+		for _, pattern := range patterns {
+			if ok, _ := path.Match(pattern, name); ok {
+				return true
+			}
+		}
+		return false
+	}
+	if len(ic.include) > 0 && !matches(ic.include) {
+		return false
+	}
+	return !matches(ic.exclude)
+}
+
+// readLinkInfo fills speed, duplex and operstate from /sys/class/net, on systems without sysfs values from net.Interfaces stay
+func (ic *interfaceCollector) readLinkInfo(iface *models.NetworkInterface) {
+	dir := filepath.Join(ic.sysRoot, "class", "net", iface.Name)
+	read := func(name string) string {
+		// speed and duplex of a virtual or down interface fail with EINVAL
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return ""
+		}
+		return strings.TrimSpace(string(data))
+	}
+
+	// loopback and tun devices have "unknown" operstate while working, their up flag is more useful
+	if state := read("operstate"); state != "" && state != "unknown" {
+		iface.OperState = state
+	}
+	if duplex := read("duplex"); duplex != "" {
+		iface.Duplex = duplex
+	}
+	if speed, err := strconv.Atoi(read("speed")); err == nil && speed > 0 {
+		iface.Speed = speed
+	}
+	if mtu, err := strconv.Atoi(read("mtu")); err == nil {
+		iface.MTU = mtu
+	}
+}
+
+// interfaceCounterDelta is counterDelta that also handles drivers with 32 bit counters. When the counter
+// is known to wrap, a value that went down from the upper half of 2^32 wrapped, otherwise it was reset
+func interfaceCounterDelta(prev, current uint64, wraps bool) uint64 {
+	if wraps && current < prev && prev <= math.MaxUint32 && prev > math.MaxUint32/2 {
+		return math.MaxUint32 - prev + current + 1
+	}
+	return counterDelta(prev, current)
+}
+
+// countersReset tells a reset from a wrap. A driver reload resets all counters of the interface at once,
+// a 32 bit counter wraps alone while the others keep growing. A 64 bit counter never wraps
+func countersReset(prev, current gnet.IOCountersStat) bool {
+	grew := current.BytesRecv > prev.BytesRecv || current.BytesSent > prev.BytesSent ||
+		current.PacketsRecv > prev.PacketsRecv || current.PacketsSent > prev.PacketsSent
+	return !grew
+}
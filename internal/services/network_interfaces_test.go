@@ -0,0 +1,47 @@
+package services
+
+import (
+	"math"
+	"testing"
+
+	gnet "github.com/shirou/gopsutil/v3/net"
+)
+
+func TestInterfaceCounterDelta(t *testing.T) {
+	for _, tc := range []struct {
+		name          string
+		prev, current uint64
+		wraps         bool
+		want          uint64
+	}{
+		{"growth", 1000, 1500, false, 500},
+		{"32 bit wrap", math.MaxUint32 - 99, 400, true, 500},
+		{"64 bit reset in the upper half of 2^32", math.MaxUint32 - 99, 400, false, 400},
+		{"wrapping counter reset far from 2^32", 1 << 20, 400, true, 400},
+		{"reset above 2^32", 1 << 40, 400, true, 400},
+	} {
+		if got := interfaceCounterDelta(tc.prev, tc.current, tc.wraps); got != tc.want {
+			t.Errorf("%s: interfaceCounterDelta(%d, %d, %v) = %d, want %d", tc.name, tc.prev, tc.current, tc.wraps, got, tc.want)
+		}
+	}
+}
+
+func TestCountersReset(t *testing.T) {
+	prev := gnet.IOCountersStat{BytesRecv: math.MaxUint32 - 99, BytesSent: 3 << 30, PacketsRecv: 3_000_000, PacketsSent: 2_000_000}
+
+	// bytes received wrapped, packets kept counting
+	wrapped := prev
+	wrapped.BytesRecv, wrapped.PacketsRecv = 400, prev.PacketsRecv+2
+	if countersReset(prev, wrapped) {
+		t.Error("a wrap of one counter is taken for a reset")
+	}
+
+	// driver reload with the same ifindex, everything starts from zero
+	reloaded := gnet.IOCountersStat{BytesRecv: 400, BytesSent: 120, PacketsRecv: 2, PacketsSent: 1}
+	if !countersReset(prev, reloaded) {
+		t.Error("a reset of all counters is taken for a wrap")
+	}
+	if delta := interfaceCounterDelta(prev.BytesRecv, reloaded.BytesRecv, !countersReset(prev, reloaded)); delta != 400 {
+		t.Errorf("bytes after reload = %d, want 400", delta)
+	}
+}
@@ -0,0 +1,220 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"syspulse/internal/models"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/host"
+)
+
+const (
+	trafficStateFile    = "traffic.json"
+	trafficSaveInterval = time.Minute
+	trafficKeepDays     = 62 // two full months of daily values
+	trafficKeepMonths   = 24
+)
+
+type trafficBytes struct {
+	Rx uint64 `json:"rx"`
+	Tx uint64 `json:"tx"`
+}
+
+// trafficCounters are raw interface counters at the moment of the last accounting
+type trafficCounters struct {
+	Index int    `json:"index"`
+	Rx    uint64 `json:"rx"`
+	Tx    uint64 `json:"tx"`
+}
+
+// trafficState is what is kept in the state dir
+type trafficState struct {
+	Since    time.Time                          `json:"since"`
+	BootTime uint64                             `json:"boot_time"` // counters are only comparable within one boot
+	Counters map[string]trafficCounters         `json:"counters"`
+	Totals   map[string]trafficBytes            `json:"totals"`
+	Daily    map[string]map[string]trafficBytes `json:"daily"`   // day -> interface -> bytes
+	Monthly  map[string]map[string]trafficBytes `json:"monthly"` // month -> interface -> bytes
+}
+
+// trafficAccounting sums byte deltas of interface counters into totals, days and months
+type trafficAccounting struct {
+	mu       sync.Mutex
+	path     string // empty when there is no state dir, totals then start from zero on every start
+	bootTime uint64
+	state    trafficState
+	started  bool // false until the first accounting of this run
+	lastSave time.Time
+}
+
+func newTrafficAccounting(stateDir string) *trafficAccounting {
+	ta := &trafficAccounting{state: newTrafficState()}
+	ta.bootTime, _ = host.BootTime()
+	if stateDir == "" {
+		return ta
+	}
+	ta.path = filepath.Join(stateDir, trafficStateFile)
+
+	data, err := os.ReadFile(ta.path)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Printf("❌ failed to read traffic state, totals start from zero: %v", err)
+		}
+		return ta
+	}
+	state := newTrafficState()
+	if err := json.Unmarshal(data, &state); err != nil {
+		log.Printf("❌ failed to parse %s, totals start from zero: %v", ta.path, err)
+		return ta
+	}
+	// after a reboot counters started from zero again, saved ones are of no use
+	if state.BootTime != ta.bootTime || ta.bootTime == 0 {
+		state.Counters = make(map[string]trafficCounters)
+	}
+	ta.state = state
+	return ta
+}
+
+func newTrafficState() trafficState {
+	return trafficState{
+		Since:    time.Now(),
+		Counters: make(map[string]trafficCounters),
+		Totals:   make(map[string]trafficBytes),
+		Daily:    make(map[string]map[string]trafficBytes),
+		Monthly:  make(map[string]map[string]trafficBytes),
+	}
+}
+
+// account adds traffic since the previous call. Counters seen for the first time on the first call of a run
+// (without saved counters of the same boot) are not counted, their values include traffic from before
+func (ta *trafficAccounting) account(samples map[string]interfaceSample, now time.Time) {
+	ta.mu.Lock()
+	defer ta.mu.Unlock()
+
+	day := now.Format("2006-01-02")
+	month := now.Format("2006-01")
+	counters := make(map[string]trafficCounters, len(samples))
+	for name, sample := range samples {
+		current := trafficCounters{Index: sample.index, Rx: sample.counters.BytesRecv, Tx: sample.counters.BytesSent}
+		counters[name] = current
+
+		var delta trafficBytes
+		prev, ok := ta.state.Counters[name]
+		switch {
+		case ok && prev.Index == current.Index:
+			delta = trafficBytes{Rx: interfaceCounterDelta(prev.Rx, current.Rx, sample.wraps), Tx: interfaceCounterDelta(prev.Tx, current.Tx, sample.wraps)}
+		case ta.started:
+			// the interface appeared or was recreated while we were running, all of its traffic is new
+			delta = trafficBytes{Rx: current.Rx, Tx: current.Tx}
+		}
+		if delta.Rx == 0 && delta.Tx == 0 {
+			continue
+		}
+		ta.state.Totals[name] = addTraffic(ta.state.Totals[name], delta)
+		addPeriodTraffic(ta.state.Daily, day, name, delta)
+		addPeriodTraffic(ta.state.Monthly, month, name, delta)
+	}
+	ta.state.Counters = counters
+	ta.state.BootTime = ta.bootTime
+	pruneTrafficPeriods(ta.state.Daily, trafficKeepDays)
+	pruneTrafficPeriods(ta.state.Monthly, trafficKeepMonths)
+
+	if !ta.started || now.Sub(ta.lastSave) >= trafficSaveInterval {
+		ta.save()
+		ta.lastSave = now
+	}
+	ta.started = true
+}
+
+// totals sums totals of given interfaces
+func (ta *trafficAccounting) totals(names []string) (rx, tx uint64) {
+	ta.mu.Lock()
+	defer ta.mu.Unlock()
+
+	for _, name := range names {
+		rx += ta.state.Totals[name].Rx
+		tx += ta.state.Totals[name].Tx
+	}
+	return rx, tx
+}
+
+func (ta *trafficAccounting) report() models.NetworkTraffic {
+	ta.mu.Lock()
+	defer ta.mu.Unlock()
+
+	report := models.NetworkTraffic{Since: ta.state.Since, Interfaces: []models.InterfaceTraffic{}}
+	for name, total := range ta.state.Totals {
+		report.Interfaces = append(report.Interfaces, models.InterfaceTraffic{
+			Name:    name,
+			RxBytes: total.Rx,
+			TxBytes: total.Tx,
+			Daily:   trafficPeriods(ta.state.Daily, name),
+			Monthly: trafficPeriods(ta.state.Monthly, name),
+		})
+	}
+	sort.Slice(report.Interfaces, func(i, j int) bool { return report.Interfaces[i].Name < report.Interfaces[j].Name })
+	return report
+}
+
+// save writes state to a temporary file and renames it, so a crash never leaves half a file
+func (ta *trafficAccounting) save() {
+	if ta.path == "" {
+		return
+	}
+	data, err := json.Marshal(ta.state)
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(ta.path), 0o755)
+	}
+	if err == nil {
+		err = os.WriteFile(ta.path+".tmp", data, 0o644)
+	}
+	if err == nil {
+		err = os.Rename(ta.path+".tmp", ta.path)
+	}
+	if err != nil {
+		log.Printf("❌ failed to save traffic state: %v", err)
+	}
+}
+
+func addTraffic(a, b trafficBytes) trafficBytes {
+	return trafficBytes{Rx: a.Rx + b.Rx, Tx: a.Tx + b.Tx}
+}
+
+func addPeriodTraffic(periods map[string]map[string]trafficBytes, period, name string, delta trafficBytes) {
+	if periods[period] == nil {
+		periods[period] = make(map[string]trafficBytes)
+	}
+	periods[period][name] = addTraffic(periods[period][name], delta)
+}
+
+// pruneTrafficPeriods keeps the newest periods, keys sort by time as they are formatted
+func pruneTrafficPeriods(periods map[string]map[string]trafficBytes, keep int) {
+	if len(periods) <= keep {
+		return
+	}
+	keys := make([]string, 0, len(periods))
+	for key := range periods {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys[:len(keys)-keep] {
+		delete(periods, key)
+	}
+}
+
+func trafficPeriods(periods map[string]map[string]trafficBytes, name string) []models.TrafficPeriod {
+	result := []models.TrafficPeriod{}
+	for period, interfaces := range periods {
+		if traffic, ok := interfaces[name]; ok {
+			result = append(result, models.TrafficPeriod{Period: period, RxBytes: traffic.Rx, TxBytes: traffic.Tx})
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Period > result[j].Period })
+	return result
+}
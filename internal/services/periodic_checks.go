@@ -0,0 +1,74 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// checkSchedule is how often a periodic check (probe, http check) runs and how long one run may take
+type checkSchedule struct {
+	interval time.Duration
+	timeout  time.Duration
+}
+
+// parseCheckList reads json list of check configs, as given in SYS_PULSE_PROBES and SYS_PULSE_HTTP_CHECKS
+func parseCheckList[T any](data string) ([]T, error) {
+	if strings.TrimSpace(data) == "" {
+		return nil, nil
+	}
+	var configs []T
+	if err := json.Unmarshal([]byte(data), &configs); err != nil {
+		return nil, err
+	}
+	return configs, nil
+}
+
+// checkParser validates what all periodic checks of one kind have in common: unique names and schedule
+type checkParser struct {
+	kind     string // "probe" or "http check", used in errors
+	defaults checkSchedule
+	names    map[string]bool
+}
+
+func newCheckParser(kind string, defaults checkSchedule) *checkParser {
+	return &checkParser{kind: kind, defaults: defaults, names: make(map[string]bool)}
+}
+
+// schedule checks the name and parses interval and timeout, empty ones take defaults
+func (cp *checkParser) schedule(name, interval, timeout string) (checkSchedule, error) {
+	if name == "" {
+		return checkSchedule{}, fmt.Errorf("%s needs a name", cp.kind)
+	}
+	if cp.names[name] {
+		return checkSchedule{}, fmt.Errorf("duplicate %s %q", cp.kind, name)
+	}
+	cp.names[name] = true
+
+	schedule := cp.defaults
+	var err error
+	if interval != "" {
+		if schedule.interval, err = time.ParseDuration(interval); err != nil || schedule.interval < time.Second {
+			return checkSchedule{}, fmt.Errorf("%s %q: interval must be a duration of at least 1s, e.g. %s", cp.kind, name, cp.defaults.interval)
+		}
+	}
+	if timeout != "" {
+		if schedule.timeout, err = time.ParseDuration(timeout); err != nil || schedule.timeout <= 0 || schedule.timeout > schedule.interval {
+			return checkSchedule{}, fmt.Errorf("%s %q: timeout must be a positive duration not longer than interval", cp.kind, name)
+		}
+	}
+	return schedule, nil
+}
+
+// runEvery calls run in its own goroutine right away and then every interval until the process exits
+func runEvery(interval time.Duration, run func()) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			run()
+			<-ticker.C
+		}
+	}()
+}
@@ -0,0 +1,166 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syspulse/internal/models"
+)
+
+var pressureResources = []string{"cpu", "memory", "io"}
+
+// pressureCollector reads Pressure Stall Information of the system and of chosen cgroups
+type pressureCollector struct {
+	procRoot   string
+	cgroupRoot string   // cgroup v2 mount, empty when there is none
+	patterns   []string // globs of cgroup paths relative to cgroupRoot
+	supported  bool     // kernel has PSI, checked once
+}
+
+func newPressureCollector(procRoot, sysRoot, cgroups string) *pressureCollector {
+	pc := &pressureCollector{procRoot: procRoot}
+	if root, ok := cgroupV2Root(sysRoot); ok {
+		pc.cgroupRoot = root
+	}
+	for _, pattern := range strings.Split(cgroups, ",") {
+		if pattern = strings.Trim(strings.TrimSpace(pattern), "/"); pattern != "" {
+			pc.patterns = append(pc.patterns, pattern)
+		}
+	}
+
+	// without CONFIG_PSI there is no /proc/pressure, with psi=0 on kernel command line reading fails
+	if _, err := readPressureFile(filepath.Join(procRoot, "pressure", "cpu")); err != nil {
+		log.Printf("ℹ️ Pressure stall information is not available: %v", err)
+	} else {
+		pc.supported = true
+	}
+	return pc
+}
+
+// collect returns nil when kernel has no PSI
+func (pc *pressureCollector) collect() *models.Pressure {
+	if !pc.supported {
+		return nil
+	}
+
+	pressure := &models.Pressure{}
+	pressure.CPU, pressure.Memory, pressure.IO = readPressureDir(filepath.Join(pc.procRoot, "pressure"), "")
+
+	if pc.cgroupRoot == "" {
+		return pressure
+	}
+	seen := make(map[string]bool)
+	for _, pattern := range pc.patterns {
+		matches, _ := filepath.Glob(filepath.Join(pc.cgroupRoot, pattern))
+		for _, dir := range matches {
+			if seen[dir] {
+				continue
+			}
+			seen[dir] = true
+
+			cpu, memory, io := readPressureDir(dir, ".pressure")
+			if cpu == nil && memory == nil && io == nil {
+				continue // not a cgroup or pressure is disabled for it
+			}
+			pressure.Cgroups = append(pressure.Cgroups, models.CgroupPressure{
+				Path:   "/" + strings.TrimPrefix(strings.TrimPrefix(dir, pc.cgroupRoot), "/"),
+				CPU:    cpu,
+				Memory: memory,
+				IO:     io,
+			})
+		}
+	}
+	sort.Slice(pressure.Cgroups, func(i, j int) bool { return pressure.Cgroups[i].Path < pressure.Cgroups[j].Path })
+	return pressure
+}
+
+// readPressureDir reads cpu, memory and io files, "cpu" in /proc/pressure and "cpu.pressure" in a cgroup
+func readPressureDir(dir, suffix string) (cpu, memory, io *models.PressureResource) {
+	resources := make([]*models.PressureResource, len(pressureResources))
+	for i, name := range pressureResources {
+		if resource, err := readPressureFile(filepath.Join(dir, name+suffix)); err == nil {
+			resources[i] = resource
+		}
+	}
+	return resources[0], resources[1], resources[2]
+}
+
+// readPressureFile parses lines like "some avg10=0.12 avg60=0.05 avg300=0.01 total=123456"
+func readPressureFile(path string) (*models.PressureResource, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	resource := &models.PressureResource{}
+	foundSome := false
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		var stat models.PressureStat
+		for _, field := range fields[1:] {
+			key, value, _ := strings.Cut(field, "=")
+			switch key {
+			case "avg10":
+				stat.Avg10, _ = strconv.ParseFloat(value, 64)
+			case "avg60":
+				stat.Avg60, _ = strconv.ParseFloat(value, 64)
+			case "avg300":
+				stat.Avg300, _ = strconv.ParseFloat(value, 64)
+			case "total":
+				stat.Total, _ = strconv.ParseUint(value, 10, 64)
+			}
+		}
+
+		switch fields[0] {
+		case "some":
+			resource.Some = stat
+			foundSome = true
+		case "full":
+			resource.Full = &stat
+		}
+	}
+	if !foundSome {
+		return nil, fmt.Errorf("no some line in %s", path)
+	}
+	return resource, nil
+}
+
+// cgroupV2Root finds unified cgroup hierarchy, mounted at fs/cgroup or at fs/cgroup/unified in hybrid mode
+func cgroupV2Root(sysRoot string) (string, bool) {
+	for _, dir := range []string{filepath.Join(sysRoot, "fs/cgroup"), filepath.Join(sysRoot, "fs/cgroup/unified")} {
+		if _, err := os.Stat(filepath.Join(dir, "cgroup.controllers")); err == nil {
+			return dir, true
+		}
+	}
+	return "", false
+}
+
+// pressureValue picks one number for alert rules, kind is some or full, avg is avg10, avg60 or avg300
+func pressureValue(resource *models.PressureResource, kind, avg string) (float64, bool) {
+	if resource == nil {
+		return 0, false
+	}
+	stat := &resource.Some
+	if kind == "full" {
+		if resource.Full == nil {
+			return 0, false
+		}
+		stat = resource.Full
+	}
+	switch avg {
+	case "avg10":
+		return stat.Avg10, true
+	case "avg60":
+		return stat.Avg60, true
+	default:
+		return stat.Avg300, true
+	}
+}
@@ -0,0 +1,308 @@
+package services
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"syspulse/internal/models"
+	"time"
+)
+
+const (
+	defaultProbeInterval = 10 * time.Second
+	defaultProbeTimeout  = 2 * time.Second
+	defaultProbeWindow   = 20
+)
+
+// probeAttempt is one try of a probe, latency is zero for failed ones
+type probeAttempt struct {
+	latency time.Duration
+	err     error
+}
+
+type probe struct {
+	config models.ProbeConfig
+	checkSchedule
+	window int
+	run    func(ctx context.Context) error
+
+	mu        sync.Mutex
+	attempts  []probeAttempt
+	lastCheck time.Time
+}
+
+// probeRunner runs every probe in its own goroutine, collection only reads results
+type probeRunner struct {
+	probes []*probe
+}
+
+// newProbeRunner parses SYS_PULSE_PROBES, nil without probes
+func newProbeRunner(data string) (*probeRunner, error) {
+	configs, err := parseCheckList[models.ProbeConfig](data)
+	if err != nil || len(configs) == 0 {
+		return nil, err
+	}
+
+	pr := &probeRunner{}
+	parser := newCheckParser("probe", checkSchedule{interval: defaultProbeInterval, timeout: defaultProbeTimeout})
+	for _, config := range configs {
+		schedule, err := parser.schedule(config.Name, config.Interval, config.Timeout)
+		if err != nil {
+			return nil, err
+		}
+		if config.Target == "" {
+			return nil, fmt.Errorf("probe %q needs a target", config.Name)
+		}
+
+		p := &probe{config: config, checkSchedule: schedule, window: defaultProbeWindow}
+		if config.Window < 0 {
+			return nil, fmt.Errorf("probe %q: window must be positive", config.Name)
+		} else if config.Window > 0 {
+			p.window = config.Window
+		}
+
+		switch config.Type {
+		case "tcp":
+			if _, _, err := net.SplitHostPort(config.Target); err != nil {
+				return nil, fmt.Errorf("probe %q: tcp target must be host:port", config.Name)
+			}
+			p.run = func(ctx context.Context) error { return tcpProbe(ctx, config.Target) }
+		case "icmp":
+			p.run = func(ctx context.Context) error { return icmpProbe(ctx, config.Target) }
+		case "dns":
+			server := config.Target
+			if _, _, err := net.SplitHostPort(server); err != nil {
+				server = net.JoinHostPort(server, "53")
+			}
+			query := config.Query
+			if query == "" {
+				query = "."
+			}
+			p.run = func(ctx context.Context) error { return dnsProbe(ctx, server, query) }
+		default:
+			return nil, fmt.Errorf("probe %q: type must be tcp, icmp or dns", config.Name)
+		}
+		pr.probes = append(pr.probes, p)
+	}
+	return pr, nil
+}
+
+// start runs probes until the process exits
+func (pr *probeRunner) start() {
+	for _, p := range pr.probes {
+		runEvery(p.interval, p.attempt)
+	}
+}
+
+func (p *probe) attempt() {
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := p.run(ctx)
+	attempt := probeAttempt{err: err}
+	if err == nil {
+		attempt.latency = time.Since(start)
+	}
+
+	p.mu.Lock()
+	p.attempts = append(p.attempts, attempt)
+	if len(p.attempts) > p.window {
+		p.attempts = p.attempts[len(p.attempts)-p.window:]
+	}
+	p.lastCheck = start
+	p.mu.Unlock()
+}
+
+// results returns probes in configured order, nil without probes
+func (pr *probeRunner) results() []models.ProbeResult {
+	if pr == nil || len(pr.probes) == 0 {
+		return nil
+	}
+	results := make([]models.ProbeResult, 0, len(pr.probes))
+	for _, p := range pr.probes {
+		results = append(results, p.result())
+	}
+	return results
+}
+
+func (p *probe) result() models.ProbeResult {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	result := models.ProbeResult{
+		Name:      p.config.Name,
+		Type:      p.config.Type,
+		Target:    p.config.Target,
+		Status:    "unknown",
+		Attempts:  len(p.attempts),
+		LastCheck: p.lastCheck,
+	}
+	if len(p.attempts) == 0 {
+		return result
+	}
+
+	last := p.attempts[len(p.attempts)-1]
+	if last.err != nil {
+		result.Status = "down"
+		result.LastError = last.err.Error()
+	} else {
+		result.Status = "up"
+	}
+
+	var sum, diffs float64
+	var succeeded, pairs int
+	prev := -1.0
+	for _, attempt := range p.attempts {
+		if attempt.err != nil {
+			continue
+		}
+		ms := float64(attempt.latency) / float64(time.Millisecond)
+		result.Latency = ms
+		sum += ms
+		succeeded++
+		if prev >= 0 {
+			diffs += math.Abs(ms - prev)
+			pairs++
+		}
+		prev = ms
+	}
+	if succeeded > 0 {
+		result.AvgLatency = sum / float64(succeeded)
+	}
+	if pairs > 0 {
+		result.Jitter = diffs / float64(pairs)
+	}
+	result.Loss = float64(len(p.attempts)-succeeded) / float64(len(p.attempts)) * 100
+	return result
+}
+
+func tcpProbe(ctx context.Context, target string) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", target)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// icmpProbe sends one echo request over a raw socket, which needs root or CAP_NET_RAW
+func icmpProbe(ctx context.Context, target string) error {
+	addr, err := net.DefaultResolver.LookupIPAddr(ctx, target)
+	if err != nil {
+		return err
+	}
+	if len(addr) == 0 {
+		return fmt.Errorf("no address for %s", target)
+	}
+	ip := addr[0].IP
+
+	network, echoType, replyType := "ip4:icmp", byte(8), byte(0)
+	if ip.To4() == nil {
+		network, echoType, replyType = "ip6:ipv6-icmp", 128, 129 // kernel computes icmpv6 checksum
+	}
+	conn, err := net.ListenPacket(network, "")
+	if err != nil {
+		if errors.Is(err, os.ErrPermission) {
+			return fmt.Errorf("icmp needs root or CAP_NET_RAW: %w", err)
+		}
+		return err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	id, seq := uint16(os.Getpid()), uint16(rand.N(math.MaxUint16))
+	request := make([]byte, 16)
+	request[0] = echoType
+	binary.BigEndian.PutUint16(request[4:], id)
+	binary.BigEndian.PutUint16(request[6:], seq)
+	copy(request[8:], "syspulse")
+	if echoType == 8 {
+		binary.BigEndian.PutUint16(request[2:], icmpChecksum(request))
+	}
+	if _, err := conn.WriteTo(request, &net.IPAddr{IP: ip}); err != nil {
+		return err
+	}
+
+	// a raw socket gets every icmp packet of the host, only our reply counts
+	reply := make([]byte, 1500)
+	for {
+		n, from, err := conn.ReadFrom(reply)
+		if err != nil {
+			return err
+		}
+		if n < 8 || reply[0] != replyType || !from.(*net.IPAddr).IP.Equal(ip) {
+			continue
+		}
+		if binary.BigEndian.Uint16(reply[4:]) == id && binary.BigEndian.Uint16(reply[6:]) == seq {
+			return nil
+		}
+	}
+}
+
+func icmpChecksum(data []byte) uint16 {
+	var sum uint32
+	for i := 0; i+1 < len(data); i += 2 {
+		sum += uint32(data[i])<<8 | uint32(data[i+1])
+	}
+	if len(data)%2 == 1 {
+		sum += uint32(data[len(data)-1]) << 8
+	}
+	for sum > 0xffff {
+		sum = sum>>16 + sum&0xffff
+	}
+	return ^uint16(sum)
+}
+
+// dnsProbe asks for NS records of query over udp, any answer but SERVFAIL and REFUSED counts, NXDOMAIN included
+func dnsProbe(ctx context.Context, server, query string) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "udp", server)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	id := uint16(rand.N(math.MaxUint16))
+	request := []byte{byte(id >> 8), byte(id), 0x01, 0x00, 0, 1, 0, 0, 0, 0, 0, 0} // recursion desired, one question
+	for _, label := range strings.Split(strings.Trim(query, "."), ".") {
+		if label == "" {
+			continue
+		}
+		if len(label) > 63 {
+			return fmt.Errorf("dns label %q is too long", label)
+		}
+		request = append(request, byte(len(label)))
+		request = append(request, label...)
+	}
+	request = append(request, 0, 0, 2, 0, 1) // root, type NS, class IN
+	if _, err := conn.Write(request); err != nil {
+		return err
+	}
+
+	reply := make([]byte, 512)
+	for {
+		n, err := conn.Read(reply)
+		if err != nil {
+			return err
+		}
+		if n >= 12 && binary.BigEndian.Uint16(reply) == id && reply[2]&0x80 != 0 {
+			if rcode := reply[3] & 0x0f; rcode == 2 || rcode == 5 {
+				return fmt.Errorf("dns server answered with rcode %d", rcode)
+			}
+			return nil
+		}
+	}
+}
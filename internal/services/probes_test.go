@@ -0,0 +1,188 @@
+package services
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"net"
+	"strings"
+	"syspulse/internal/models"
+	"testing"
+	"time"
+)
+
+func TestTCPProbe(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := tcpProbe(ctx, listener.Addr().String()); err != nil {
+		t.Errorf("probe of open port failed: %v", err)
+	}
+
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closedAddr := closed.Addr().String()
+	closed.Close()
+	if err := tcpProbe(ctx, closedAddr); err == nil {
+		t.Error("probe of closed port succeeded")
+	}
+}
+
+// startDNSResponder answers every query with reply, which gets the query and returns the answer or nil for none
+func startDNSResponder(t *testing.T, reply func(query []byte) []byte) string {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, from, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			if answer := reply(buf[:n]); answer != nil {
+				conn.WriteTo(answer, from)
+			}
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+// dnsAnswer is a header-only response to query with rcode, id is changed by idOffset
+func dnsAnswer(query []byte, rcode byte, idOffset uint16) []byte {
+	answer := append([]byte(nil), query...)
+	binary.BigEndian.PutUint16(answer, binary.BigEndian.Uint16(query)+idOffset)
+	answer[2] |= 0x80 // response
+	answer[3] = answer[3]&0xf0 | rcode
+	return answer
+}
+
+func TestDNSProbe(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		reply   func([]byte) []byte
+		wantErr string
+	}{
+		{"noerror", func(q []byte) []byte { return dnsAnswer(q, 0, 0) }, ""},
+		{"nxdomain", func(q []byte) []byte { return dnsAnswer(q, 3, 0) }, ""},
+		{"servfail", func(q []byte) []byte { return dnsAnswer(q, 2, 0) }, "rcode 2"},
+		{"refused", func(q []byte) []byte { return dnsAnswer(q, 5, 0) }, "rcode 5"},
+		{"wrong id", func(q []byte) []byte { return dnsAnswer(q, 0, 1) }, "timeout"},
+		{"no answer", func([]byte) []byte { return nil }, "timeout"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			questions := make(chan string, 1)
+			server := startDNSResponder(t, func(query []byte) []byte {
+				select {
+				case questions <- string(query[12:]):
+				default:
+				}
+				return tc.reply(query)
+			})
+
+			ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+			defer cancel()
+			err := dnsProbe(ctx, server, "example.org.")
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Fatalf("probe failed: %v", err)
+				}
+				if question, want := <-questions, "\x07example\x03org\x00\x00\x02\x00\x01"; question != want {
+					t.Errorf("question = %q, want %q", question, want)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("err = %v, want %q", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestProbeResultMath(t *testing.T) {
+	failed := errors.New("connection refused")
+	p := &probe{
+		config: models.ProbeConfig{Name: "gw", Type: "tcp", Target: "10.0.0.1:22"},
+		attempts: []probeAttempt{
+			{latency: 10 * time.Millisecond},
+			{err: failed},
+			{latency: 20 * time.Millisecond},
+			{latency: 15 * time.Millisecond},
+		},
+	}
+
+	result := p.result()
+	if result.Status != "up" || result.Attempts != 4 {
+		t.Errorf("status %s with %d attempts, want up with 4", result.Status, result.Attempts)
+	}
+	assertNear(t, "loss", result.Loss, 25, 0.001)
+	assertNear(t, "latency", result.Latency, 15, 0.001)
+	assertNear(t, "average latency", result.AvgLatency, 15, 0.001)
+	assertNear(t, "jitter", result.Jitter, 7.5, 0.001) // |20-10| and |15-20|, the failed attempt is skipped
+
+	p.attempts = append(p.attempts, probeAttempt{err: failed})
+	result = p.result()
+	if result.Status != "down" || result.LastError != failed.Error() {
+		t.Errorf("status %s with error %q, want down with %q", result.Status, result.LastError, failed)
+	}
+	assertNear(t, "loss", result.Loss, 40, 0.001)
+	assertNear(t, "latency of last success", result.Latency, 15, 0.001)
+
+	p.attempts = []probeAttempt{{err: failed}, {err: failed}}
+	result = p.result()
+	if result.Loss != 100 || result.AvgLatency != 0 || result.Jitter != 0 {
+		t.Errorf("all failed: loss %.0f, avg %.1f, jitter %.1f", result.Loss, result.AvgLatency, result.Jitter)
+	}
+
+	if result := (&probe{config: p.config}).result(); result.Status != "unknown" {
+		t.Errorf("status without attempts = %s, want unknown", result.Status)
+	}
+}
+
+func TestProbeConfigValidation(t *testing.T) {
+	for _, tc := range []struct {
+		config  string
+		wantErr string
+	}{
+		{`[{"type":"tcp","target":"a:1"}]`, "needs a name"},
+		{`[{"name":"a","type":"tcp","target":"a:1"},{"name":"a","type":"tcp","target":"b:1"}]`, `duplicate probe "a"`},
+		{`[{"name":"a","type":"tcp"}]`, "needs a target"},
+		{`[{"name":"a","type":"tcp","target":"host"}]`, "host:port"},
+		{`[{"name":"a","type":"udp","target":"a:1"}]`, "type must be tcp, icmp or dns"},
+		{`[{"name":"a","type":"tcp","target":"a:1","interval":"500ms"}]`, "at least 1s"},
+		{`[{"name":"a","type":"tcp","target":"a:1","interval":"often"}]`, "at least 1s"},
+		{`[{"name":"a","type":"tcp","target":"a:1","interval":"5s","timeout":"10s"}]`, "not longer than interval"},
+		{`[{"name":"a","type":"tcp","target":"a:1","timeout":"-1s"}]`, "positive duration"},
+		{`[{"name":"a","type":"tcp","target":"a:1","window":-1}]`, "window must be positive"},
+		{`{"name":"a"}`, "cannot unmarshal"},
+	} {
+		if _, err := newProbeRunner(tc.config); err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+			t.Errorf("newProbeRunner(%s) err = %v, want %q", tc.config, err, tc.wantErr)
+		}
+	}
+
+	pr, err := newProbeRunner(`[{"name":"dns","type":"dns","target":"1.1.1.1"},{"name":"ping","type":"icmp","target":"gw","interval":"5s","timeout":"1s","window":5}]`)
+	if err != nil || len(pr.probes) != 2 {
+		t.Fatalf("valid probes: %v, %v", pr, err)
+	}
+	if p := pr.probes[1]; p.interval != 5*time.Second || p.timeout != time.Second || p.window != 5 {
+		t.Errorf("ping probe every %v with timeout %v and window %d", p.interval, p.timeout, p.window)
+	}
+	if p := pr.probes[0]; p.interval != defaultProbeInterval || p.timeout != defaultProbeTimeout || p.window != defaultProbeWindow {
+		t.Errorf("dns probe without schedule every %v with timeout %v and window %d", p.interval, p.timeout, p.window)
+	}
+	if pr, err := newProbeRunner("  "); pr != nil || err != nil {
+		t.Errorf("empty config = %v, %v, want nothing", pr, err)
+	}
+}
@@ -0,0 +1,585 @@
+package services
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syspulse/internal/models"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// USER_HZ, the unit of cpu times in /proc. It is 100 on every arch linux exposes to userspace,
+// processCache still takes the real value from the auxiliary vector and this is only the fallback
+const userHZ = 100
+
+// AT_CLKTCK entry of the auxiliary vector, sysconf(_SC_CLK_TCK) reads the same
+const atClockTick = 17
+
+// processSample is what we read about one process on every tick
+type processSample struct {
+	PID        int32
+	PPID       int32
+	Name       string
+	Status     string  // gopsutil status word: running, sleep, etc.
+	CPUTime    float64 // user + system cpu seconds since start
+	CreateTime int64   // ms since epoch, together with pid identifies a process
+	Threads    int
+	RSS        uint64
+}
+
+// processEntry is cached between ticks while the process lives
+type processEntry struct {
+	pid         int32
+	ppid        int32
+	createTime  int64
+	name        string
+	commandLine string
+	user        string
+	cgroup      string
+	cpuTime     float64   // cpu seconds at last sample
+	cpuPercent  float64   // usage at last sample, reported when process exits
+	rss         uint64    // usage at last sample, reported when process exits
+	threads     int       // threads at last sample, for short-lived process estimate
+	sampledAt   time.Time // time of last sample
+	generation  uint64    // last collection that saw this process
+}
+
+// processCache keeps processes between collections, so cpu usage is computed from the delta between two samples
+// instead of lifetime average that gopsutil CPUPercent gives
+type processCache struct {
+	procRoot   string
+	useProcfs  bool
+	normalize  bool // divide cpu % by amount of cores, so 100% means all cores are busy
+	cores      int
+	bootTime   int64 // seconds since epoch, from /proc/stat btime
+	clockTicks int64 // unit of cpu times in /proc/[pid]/stat
+	pageSize   uint64
+	entries    map[int32]*processEntry
+	users      map[string]string // uid -> user name
+	generation uint64
+	forks      uint64 // "processes" counter of /proc/stat at last collection
+	stats      models.ProcessStats
+}
+
+func newProcessCache(procRoot string, normalize bool, cores int) *processCache {
+	if cores < 1 {
+		cores = 1
+	}
+	pc := &processCache{
+		procRoot:  procRoot,
+		normalize: normalize,
+		cores:     cores,
+		pageSize:  uint64(os.Getpagesize()),
+		entries:   make(map[int32]*processEntry),
+		users:     make(map[string]string),
+	}
+	if bootTime, err := readBootTime(procRoot); err == nil {
+		pc.bootTime = bootTime
+		pc.useProcfs = true
+		pc.clockTicks = readClockTicks(procRoot)
+	}
+	return pc
+}
+
+// collect samples all processes, memTotal is used for memory percent. Processes appeared or gone since
+// previous collection are returned as events, first collection gives no events
+func (pc *processCache) collect(memTotal uint64) ([]models.ProcessInfo, []models.Event, *models.ProcessStats) {
+	pids, err := pc.listPIDs()
+	if err != nil {
+		return []models.ProcessInfo{}, nil, nil
+	}
+
+	pc.generation++
+	now := time.Now()
+	processMetrics := make([]models.ProcessInfo, 0, len(pids))
+	firstRun := pc.generation == 1
+
+	var events []models.Event
+	started, exited := 0, 0
+	newThreads := 0 // threads created since previous collection, they are counted as forks too
+
+	for _, pid := range pids {
+		sample, err := pc.sample(pid)
+		if err != nil || sample.Status == process.Zombie {
+			continue
+		}
+
+		entry, ok := pc.entries[pid]
+		cpuPercent := 0.0
+		if ok && entry.createTime == sample.CreateTime {
+			if elapsed := now.Sub(entry.sampledAt).Seconds(); elapsed > 0 {
+				cpuPercent = (sample.CPUTime - entry.cpuTime) / elapsed * 100
+				if cpuPercent < 0 {
+					cpuPercent = 0
+				}
+				if pc.normalize {
+					cpuPercent /= float64(pc.cores)
+				}
+			}
+			newThreads += max(sample.Threads-entry.threads, 0)
+		} else {
+			// new process or pid was reused by another one, first sample has nothing to compare with
+			if ok {
+				events = append(events, exitedEvent(entry, now))
+				exited++
+			}
+			entry = pc.newEntry(sample)
+			pc.entries[pid] = entry
+			if !firstRun {
+				events = append(events, startedEvent(entry, now))
+				started++
+				newThreads += max(sample.Threads-1, 0)
+			}
+		}
+		entry.ppid = sample.PPID
+		entry.cpuTime = sample.CPUTime
+		entry.cpuPercent = cpuPercent
+		entry.rss = sample.RSS
+		entry.threads = sample.Threads
+		entry.sampledAt = now
+		entry.generation = pc.generation
+
+		processMetrics = append(processMetrics, processInfo(entry, sample, cpuPercent, memTotal))
+	}
+
+	for pid, entry := range pc.entries { // forgetting processes that are gone
+		if entry.generation != pc.generation {
+			events = append(events, exitedEvent(entry, now))
+			exited++
+			delete(pc.entries, pid)
+		}
+	}
+
+	stats := pc.updateStats(len(processMetrics), started, exited, newThreads, firstRun)
+	return processMetrics, events, stats
+}
+
+// updateStats counts process churn. Processes that started and exited between two collections are never seen,
+// they are estimated from the fork counter of /proc/stat minus new processes and threads we have seen.
+// Threads that lived shorter than a collection interval are counted as short-lived processes too
+func (pc *processCache) updateStats(running, started, exited, newThreads int, firstRun bool) *models.ProcessStats {
+	pc.stats.Running = running
+	pc.stats.Started = started
+	pc.stats.Exited = exited
+	pc.stats.ShortLived = 0
+	pc.stats.TotalStarted += uint64(started)
+	pc.stats.TotalExited += uint64(exited)
+
+	if pc.useProcfs {
+		if forks, err := readForkCount(pc.procRoot); err == nil {
+			if !firstRun && forks > pc.forks {
+				if seen := uint64(started + newThreads); forks-pc.forks > seen {
+					pc.stats.ShortLived = forks - pc.forks - seen
+				}
+			}
+			pc.forks = forks
+		}
+	}
+	pc.stats.TotalShortLived += pc.stats.ShortLived
+
+	stats := pc.stats
+	return &stats
+}
+
+func startedEvent(entry *processEntry, now time.Time) models.Event {
+	return models.Event{
+		ID:        processEventID(models.EventProcessStarted, entry),
+		Type:      models.EventProcessStarted,
+		Timestamp: now,
+		Message:   fmt.Sprintf("process %s (%d) started", entry.name, entry.pid),
+		Process:   processEventInfo(entry),
+	}
+}
+
+func exitedEvent(entry *processEntry, now time.Time) models.Event {
+	info := processEventInfo(entry)
+	// exit happened somewhere between last sample and now, last sample is the best we know
+	info.Lifetime = entry.sampledAt.Sub(time.UnixMilli(entry.createTime)).Seconds()
+	info.CPUPercent = entry.cpuPercent
+	info.MemoryRSS = entry.rss
+
+	return models.Event{
+		ID:        processEventID(models.EventProcessExited, entry),
+		Type:      models.EventProcessExited,
+		Timestamp: now,
+		Message:   fmt.Sprintf("process %s (%d) exited after %s", entry.name, entry.pid, time.Duration(info.Lifetime*float64(time.Second)).Round(time.Second)),
+		Process:   info,
+	}
+}
+
+func processEventInfo(entry *processEntry) *models.ProcessEventInfo {
+	return &models.ProcessEventInfo{
+		PID:         entry.pid,
+		PPID:        entry.ppid,
+		Name:        entry.name,
+		CommandLine: entry.commandLine,
+		User:        entry.user,
+		Cgroup:      entry.cgroup,
+		CreateTime:  entry.createTime / 1000,
+	}
+}
+
+// pid and create time identify a process, so the id is unique for each event type
+func processEventID(eventType string, entry *processEntry) string {
+	return fmt.Sprintf("%s-%d-%d", eventType, entry.pid, entry.createTime)
+}
+
+func processInfo(entry *processEntry, sample processSample, cpuPercent float64, memTotal uint64) models.ProcessInfo {
+	info := models.ProcessInfo{
+		PID:         sample.PID,
+		PPID:        sample.PPID,
+		Process:     entry.name,
+		CPUPercent:  cpuPercent,
+		MemoryRSS:   sample.RSS,
+		Status:      sample.Status,
+		CommandLine: entry.commandLine,
+		User:        entry.user,
+		CreateTime:  sample.CreateTime / 1000,
+		Threads:     sample.Threads,
+		Cgroup:      entry.cgroup,
+	}
+	if memTotal > 0 {
+		info.MemoryPercent = float64(sample.RSS) / float64(memTotal) * 100
+	}
+	return info
+}
+
+// liveProcess reads a process the last collection did not see, e.g. started after it. It runs outside
+// collection and leaves the cache alone, cpu usage needs two samples and is 0
+func (pc *processCache) liveProcess(sample processSample, memTotal uint64) models.ProcessInfo {
+	entry := pc.describe(sample)
+	if uid, err := readProcUID(pc.procRoot, sample.PID); err == nil {
+		entry.user = lookupUserName(uid)
+	}
+	return processInfo(entry, sample, 0, memTotal)
+}
+
+func (pc *processCache) newEntry(sample processSample) *processEntry {
+	entry := pc.describe(sample)
+	entry.user = pc.readUser(sample.PID)
+	return entry
+}
+
+// describe reads what does not change while the process lives, except the user, which is cached
+func (pc *processCache) describe(sample processSample) *processEntry {
+	entry := &processEntry{
+		pid:         sample.PID,
+		ppid:        sample.PPID,
+		createTime:  sample.CreateTime,
+		name:        sample.Name,
+		commandLine: "N/A",
+	}
+
+	if cmdline, err := pc.readCmdline(sample.PID); err == nil {
+		if cmdline == "" {
+			entry.commandLine = "System process"
+		} else {
+			entry.commandLine = cmdline
+			// comm is cut to 15 chars, full name can be taken from cmdline
+			if args := strings.Fields(cmdline); len(entry.name) == 15 && len(args) > 0 {
+				if base := filepath.Base(args[0]); strings.HasPrefix(base, entry.name) {
+					entry.name = base
+				}
+			}
+		}
+	}
+
+	if pc.useProcfs {
+		entry.cgroup, _ = readProcCgroup(pc.procRoot, sample.PID)
+	}
+	return entry
+}
+
+func (pc *processCache) listPIDs() ([]int32, error) {
+	if !pc.useProcfs {
+		return process.Pids()
+	}
+
+	dirs, err := os.ReadDir(pc.procRoot)
+	if err != nil {
+		return nil, err
+	}
+	pids := make([]int32, 0, len(dirs))
+	for _, dir := range dirs {
+		if pid, err := strconv.ParseInt(dir.Name(), 10, 32); err == nil && dir.IsDir() {
+			pids = append(pids, int32(pid))
+		}
+	}
+	return pids, nil
+}
+
+func (pc *processCache) sample(pid int32) (processSample, error) {
+	if !pc.useProcfs {
+		return sampleWithGopsutil(pid)
+	}
+
+	stat, err := readProcStat(pc.procRoot, pid)
+	if err != nil {
+		return processSample{}, err
+	}
+
+	sample := processSample{
+		PID:        pid,
+		PPID:       stat.PPID,
+		Name:       stat.Comm,
+		Status:     convertProcState(stat.State),
+		CPUTime:    float64(stat.UTime+stat.STime) / float64(pc.clockTicks),
+		CreateTime: pc.bootTime*1000 + int64(stat.StartTime)*1000/pc.clockTicks,
+		Threads:    stat.Threads,
+	}
+
+	if statm, err := os.ReadFile(filepath.Join(pc.procRoot, strconv.Itoa(int(pid)), "statm")); err == nil {
+		if fields := strings.Fields(string(statm)); len(fields) > 1 {
+			resident, _ := strconv.ParseUint(fields[1], 10, 64)
+			sample.RSS = resident * pc.pageSize
+		}
+	}
+
+	return sample, nil
+}
+
+// sampleWithGopsutil is used where there is no procfs
+func sampleWithGopsutil(pid int32) (processSample, error) {
+	p, err := process.NewProcess(pid)
+	if err != nil {
+		return processSample{}, err
+	}
+
+	status, err := p.Status()
+	if err != nil || len(status) == 0 {
+		return processSample{}, fmt.Errorf("failed to get status of process %d: %w", pid, err)
+	}
+
+	sample := processSample{PID: pid, Status: status[0], Threads: -1}
+	sample.Name, _ = p.Name()
+	sample.PPID, _ = p.Ppid()
+	sample.CreateTime, _ = p.CreateTime()
+	if times, err := p.Times(); err == nil {
+		sample.CPUTime = times.User + times.System
+	}
+	if memInfo, err := p.MemoryInfo(); err == nil {
+		sample.RSS = memInfo.RSS
+	}
+	if threads, err := p.NumThreads(); err == nil {
+		sample.Threads = int(threads)
+	}
+	return sample, nil
+}
+
+func (pc *processCache) readCmdline(pid int32) (string, error) {
+	if !pc.useProcfs {
+		p, err := process.NewProcess(pid)
+		if err != nil {
+			return "", err
+		}
+		return p.Cmdline()
+	}
+
+	data, err := os.ReadFile(filepath.Join(pc.procRoot, strconv.Itoa(int(pid)), "cmdline"))
+	if err != nil {
+		return "", err
+	}
+	data = bytes.TrimRight(data, "\x00")
+	return string(bytes.ReplaceAll(data, []byte{0}, []byte{' '})), nil
+}
+
+func (pc *processCache) readUser(pid int32) string {
+	if !pc.useProcfs {
+		p, err := process.NewProcess(pid)
+		if err != nil {
+			return ""
+		}
+		name, _ := p.Username()
+		return name
+	}
+
+	uid, err := readProcUID(pc.procRoot, pid)
+	if err != nil {
+		return ""
+	}
+	if name, ok := pc.users[uid]; ok {
+		return name
+	}
+
+	name := lookupUserName(uid)
+	pc.users[uid] = name
+	return name
+}
+
+// lookupUserName returns the user name of uid, or uid itself for a user without a name
+func lookupUserName(uid string) string {
+	if u, err := user.LookupId(uid); err == nil {
+		return u.Username
+	}
+	return uid
+}
+
+// ─── procfs ──────────────────────────────────────────────────────────────────
+
+// procStat holds fields of /proc/[pid]/stat we need
+type procStat struct {
+	Comm      string
+	State     string
+	PPID      int32
+	UTime     uint64 // clock ticks
+	STime     uint64 // clock ticks
+	Threads   int
+	StartTime uint64 // clock ticks after boot
+}
+
+func readProcStat(procRoot string, pid int32) (procStat, error) {
+	data, err := os.ReadFile(filepath.Join(procRoot, strconv.Itoa(int(pid)), "stat"))
+	if err != nil {
+		return procStat{}, err
+	}
+	return parseProcStat(string(data))
+}
+
+func parseProcStat(data string) (procStat, error) {
+	// comm can contain spaces and brackets, so we split by the last ")"
+	open := strings.IndexByte(data, '(')
+	closing := strings.LastIndexByte(data, ')')
+	if open < 0 || closing < open {
+		return procStat{}, fmt.Errorf("malformed stat line")
+	}
+
+	fields := strings.Fields(data[closing+1:])
+	if len(fields) < 20 { // starttime is field 22, 20th after comm
+		return procStat{}, fmt.Errorf("stat line has only %d fields", len(fields))
+	}
+
+	stat := procStat{
+		Comm:  data[open+1 : closing],
+		State: fields[0],
+	}
+	ppid, _ := strconv.ParseInt(fields[1], 10, 32)
+	stat.PPID = int32(ppid)
+	stat.UTime, _ = strconv.ParseUint(fields[11], 10, 64)
+	stat.STime, _ = strconv.ParseUint(fields[12], 10, 64)
+	stat.Threads, _ = strconv.Atoi(fields[17])
+	stat.StartTime, _ = strconv.ParseUint(fields[19], 10, 64)
+
+	return stat, nil
+}
+
+func readProcUID(procRoot string, pid int32) (string, error) {
+	data, err := os.ReadFile(filepath.Join(procRoot, strconv.Itoa(int(pid)), "status"))
+	if err != nil {
+		return "", err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.HasPrefix(line, "Uid:") {
+			if fields := strings.Fields(line); len(fields) > 1 {
+				return fields[1], nil // real uid
+			}
+		}
+	}
+	return "", fmt.Errorf("no Uid in status of process %d", pid)
+}
+
+// readProcCgroup returns unified (v2) cgroup path of process, or path in systemd hierarchy on cgroup v1
+func readProcCgroup(procRoot string, pid int32) (string, error) {
+	data, err := os.ReadFile(filepath.Join(procRoot, strconv.Itoa(int(pid)), "cgroup"))
+	if err != nil {
+		return "", err
+	}
+
+	var systemdPath string
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		parts := strings.SplitN(line, ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		if parts[0] == "0" && parts[1] == "" {
+			return parts[2], nil
+		}
+		if parts[1] == "name=systemd" {
+			systemdPath = parts[2]
+		}
+	}
+	return systemdPath, nil
+}
+
+func readBootTime(procRoot string) (int64, error) {
+	data, err := os.ReadFile(filepath.Join(procRoot, "stat"))
+	if err != nil {
+		return 0, err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.HasPrefix(line, "btime ") {
+			return strconv.ParseInt(strings.TrimSpace(line[len("btime "):]), 10, 64)
+		}
+	}
+	return 0, fmt.Errorf("no btime in %s/stat", procRoot)
+}
+
+// readClockTicks returns AT_CLKTCK from /proc/self/auxv, pairs of native words key, value
+func readClockTicks(procRoot string) int64 {
+	data, err := os.ReadFile(filepath.Join(procRoot, "self", "auxv"))
+	if err != nil {
+		return userHZ
+	}
+	word := strconv.IntSize / 8
+	for i := 0; i+2*word <= len(data); i += 2 * word {
+		key, value := auxvWord(data[i:i+word]), auxvWord(data[i+word:i+2*word])
+		if key == atClockTick && value > 0 {
+			return int64(value)
+		}
+		if key == 0 { // AT_NULL ends the vector
+			break
+		}
+	}
+	return userHZ
+}
+
+func auxvWord(b []byte) uint64 {
+	if len(b) == 4 {
+		return uint64(binary.NativeEndian.Uint32(b))
+	}
+	return binary.NativeEndian.Uint64(b)
+}
+
+// readForkCount returns amount of forks since boot, "processes" line of /proc/stat
+func readForkCount(procRoot string) (uint64, error) {
+	data, err := os.ReadFile(filepath.Join(procRoot, "stat"))
+	if err != nil {
+		return 0, err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.HasPrefix(line, "processes ") {
+			return strconv.ParseUint(strings.TrimSpace(line[len("processes "):]), 10, 64)
+		}
+	}
+	return 0, fmt.Errorf("no processes in %s/stat", procRoot)
+}
+
+// convertProcState turns state letter from stat into the same words gopsutil uses
+func convertProcState(state string) string {
+	switch state {
+	case "R":
+		return process.Running
+	case "S":
+		return process.Sleep
+	case "D":
+		return process.Blocked
+	case "T", "t":
+		return process.Stop
+	case "I":
+		return process.Idle
+	case "Z":
+		return process.Zombie
+	case "W":
+		return process.Wait
+	case "L":
+		return process.Lock
+	default:
+		return process.UnknownState
+	}
+}
@@ -0,0 +1,203 @@
+package services
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syspulse/internal/models"
+	"testing"
+	"time"
+)
+
+const fakeBootTime = 1700000000
+
+// fakeProcfs is a procfs root in a temp dir with just the files processCache reads
+type fakeProcfs struct {
+	t    *testing.T
+	root string
+}
+
+func newFakeProcfs(t *testing.T, clockTicks uint64) *fakeProcfs {
+	t.Helper()
+	fp := &fakeProcfs{t: t, root: t.TempDir()}
+	fp.write("stat", fmt.Sprintf("cpu  0 0 0 0 0 0 0 0 0 0\nbtime %d\nprocesses 1000\n", fakeBootTime))
+
+	word := strconv.IntSize / 8
+	var auxv []byte
+	for _, value := range []uint64{6, 4096, atClockTick, clockTicks, 0, 0} {
+		b := make([]byte, word)
+		if word == 4 {
+			binary.NativeEndian.PutUint32(b, uint32(value))
+		} else {
+			binary.NativeEndian.PutUint64(b, value)
+		}
+		auxv = append(auxv, b...)
+	}
+	fp.write("self/auxv", string(auxv))
+	return fp
+}
+
+func (fp *fakeProcfs) write(name, content string) {
+	fp.t.Helper()
+	path := filepath.Join(fp.root, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		fp.t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		fp.t.Fatal(err)
+	}
+}
+
+// process writes stat of a sleeping process, cpu and start times are in clock ticks
+func (fp *fakeProcfs) process(pid int, comm string, utime, stime, startTime uint64) {
+	fp.t.Helper()
+	dir := strconv.Itoa(pid)
+	fp.write(dir+"/stat", fmt.Sprintf("%d (%s) S 1 %d %d 0 -1 0 0 0 0 0 %d %d 0 0 20 0 1 0 %d 0 0\n",
+		pid, comm, pid, pid, utime, stime, startTime))
+	fp.write(dir+"/statm", "100 10 0 0 0 0 0\n")
+	fp.write(dir+"/cmdline", "/usr/bin/"+comm+"\x00--flag\x00")
+	fp.write(dir+"/status", "Name:\t"+comm+"\nUid:\t0\t0\t0\t0\n")
+	fp.write(dir+"/cgroup", "0::/system.slice/"+comm+".service\n")
+}
+
+// backdate pretends the last sample of pid was taken d ago, so elapsed time is known
+func backdate(pc *processCache, pid int32, d time.Duration) {
+	pc.entries[pid].sampledAt = time.Now().Add(-d)
+}
+
+func findProcess(t *testing.T, processes []models.ProcessInfo, pid int32) models.ProcessInfo {
+	t.Helper()
+	for _, p := range processes {
+		if p.PID == pid {
+			return p
+		}
+	}
+	t.Fatalf("process %d not found in %v", pid, processes)
+	return models.ProcessInfo{}
+}
+
+func assertNear(t *testing.T, name string, got, want, tolerance float64) {
+	t.Helper()
+	if math.Abs(got-want) > tolerance {
+		t.Errorf("%s = %.2f, want %.2f±%.2f", name, got, want, tolerance)
+	}
+}
+
+func TestProcessCacheCPUPercentFromDelta(t *testing.T) {
+	fp := newFakeProcfs(t, 100)
+	fp.process(100, "worker", 500, 500, 1000) // 10 s of lifetime cpu must not matter
+	pc := newProcessCache(fp.root, false, 4)
+
+	processes, _, _ := pc.collect(0)
+	if got := findProcess(t, processes, 100).CPUPercent; got != 0 {
+		t.Fatalf("first sample cpu = %v, want 0", got)
+	}
+
+	backdate(pc, 100, 2*time.Second)
+	fp.process(100, "worker", 560, 540, 1000) // 1 s of cpu in 2 s
+	processes, _, _ = pc.collect(0)
+	assertNear(t, "cpu percent", findProcess(t, processes, 100).CPUPercent, 50, 1)
+
+	backdate(pc, 100, 2*time.Second)
+	fp.process(100, "worker", 760, 740, 1000) // 4 s in 2 s, two cores busy
+	processes, _, _ = pc.collect(0)
+	assertNear(t, "cpu percent above 100", findProcess(t, processes, 100).CPUPercent, 200, 4)
+}
+
+func TestProcessCacheNormalizesByCores(t *testing.T) {
+	fp := newFakeProcfs(t, 100)
+	fp.process(100, "worker", 0, 0, 1000)
+	pc := newProcessCache(fp.root, true, 4)
+	pc.collect(0)
+
+	backdate(pc, 100, 2*time.Second)
+	fp.process(100, "worker", 200, 0, 1000) // 2 s of cpu in 2 s is one of four cores
+	processes, _, _ := pc.collect(0)
+	assertNear(t, "normalized cpu percent", findProcess(t, processes, 100).CPUPercent, 25, 0.5)
+}
+
+func TestProcessCacheUsesClockTicksFromAuxv(t *testing.T) {
+	fp := newFakeProcfs(t, 250)
+	fp.process(100, "worker", 0, 0, 2500)
+	pc := newProcessCache(fp.root, false, 1)
+	if pc.clockTicks != 250 {
+		t.Fatalf("clock ticks = %d, want 250", pc.clockTicks)
+	}
+
+	processes, _, _ := pc.collect(0)
+	if got, want := findProcess(t, processes, 100).CreateTime, int64(fakeBootTime+10); got != want {
+		t.Errorf("create time = %d, want %d", got, want)
+	}
+
+	backdate(pc, 100, 2*time.Second)
+	fp.process(100, "worker", 250, 0, 2500) // 1 s at 250 Hz
+	processes, _, _ = pc.collect(0)
+	assertNear(t, "cpu percent", findProcess(t, processes, 100).CPUPercent, 50, 1)
+}
+
+func TestProcessCacheDetectsPIDReuse(t *testing.T) {
+	fp := newFakeProcfs(t, 100)
+	fp.process(100, "old", 1000, 0, 1000)
+	pc := newProcessCache(fp.root, false, 1)
+	pc.collect(0)
+
+	// same pid, later start time: the old process exited and a new one got its pid
+	backdate(pc, 100, 2*time.Second)
+	fp.process(100, "new", 50, 0, 5000)
+	processes, events, stats := pc.collect(0)
+
+	p := findProcess(t, processes, 100)
+	if p.Process != "new" || p.CPUPercent != 0 {
+		t.Errorf("reused pid = %s with %.1f%% cpu, want new with 0%%", p.Process, p.CPUPercent)
+	}
+	if len(events) != 2 || events[0].Type != models.EventProcessExited || events[1].Type != models.EventProcessStarted {
+		t.Fatalf("events = %+v, want exited and started", events)
+	}
+	if events[0].Process.Name != "old" || events[1].Process.Name != "new" {
+		t.Errorf("event processes = %s, %s, want old, new", events[0].Process.Name, events[1].Process.Name)
+	}
+	if stats.Started != 1 || stats.Exited != 1 {
+		t.Errorf("stats started %d exited %d, want 1 and 1", stats.Started, stats.Exited)
+	}
+}
+
+func TestProcessCacheForgetsExitedProcesses(t *testing.T) {
+	fp := newFakeProcfs(t, 100)
+	fp.process(100, "short", 0, 0, 1000)
+	fp.process(101, "long", 0, 0, 1000)
+	pc := newProcessCache(fp.root, false, 1)
+	pc.collect(0)
+
+	if err := os.RemoveAll(filepath.Join(fp.root, "100")); err != nil {
+		t.Fatal(err)
+	}
+	processes, events, _ := pc.collect(0)
+	if len(processes) != 1 || processes[0].PID != 101 {
+		t.Errorf("processes = %+v, want only 101", processes)
+	}
+	if len(events) != 1 || events[0].Type != models.EventProcessExited || events[0].Process.PID != 100 {
+		t.Errorf("events = %+v, want exit of 100", events)
+	}
+	if _, ok := pc.entries[100]; ok {
+		t.Error("exited process is still cached")
+	}
+}
+
+// USER_HZ is 100 on all linux architectures, the fallback relies on it
+func TestReadClockTicksOfRunningSystem(t *testing.T) {
+	if _, err := os.Stat("/proc/self/auxv"); err != nil {
+		t.Skip("no procfs")
+	}
+	if got := readClockTicks("/proc"); got != userHZ {
+		t.Errorf("AT_CLKTCK = %d, want %d", got, userHZ)
+	}
+}
+
+func TestReadClockTicksFallback(t *testing.T) {
+	if got := readClockTicks(t.TempDir()); got != userHZ {
+		t.Errorf("clock ticks without auxv = %d, want %d", got, userHZ)
+	}
+}
@@ -0,0 +1,184 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"syspulse/internal/models"
+
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+var (
+	ErrProcessNotFound = errors.New("process not found")
+	ErrProcessReused   = errors.New("create_time does not match, pid belongs to another process now")
+	ErrInvalidAction   = errors.New("invalid action")
+	ErrNotPermitted    = errors.New("operation not permitted")
+)
+
+// io scheduling classes, values are the same as in linux ioprio.h
+var ioClasses = map[string]int{
+	"realtime":    1,
+	"best-effort": 2,
+	"idle":        3,
+}
+
+// signals that can be sent from api, other ones are refused
+var allowedSignals = []string{"TERM", "KILL", "HUP", "STOP", "CONT"}
+
+// ProcessControlService changes running processes: signals, priority and cpu affinity
+type ProcessControlService struct{}
+
+func NewProcessControlService() *ProcessControlService {
+	return &ProcessControlService{}
+}
+
+func (pcs *ProcessControlService) Signal(pid int32, req models.ProcessSignalRequest) (models.ProcessActionResult, error) {
+	result := models.ProcessActionResult{PID: pid, Action: "signal", DryRun: req.DryRun}
+
+	name := strings.TrimPrefix(strings.ToUpper(req.Signal), "SIG")
+	if !isAllowedSignal(name) {
+		return result, fmt.Errorf("%w: signal must be one of %s", ErrInvalidAction, strings.Join(allowedSignals, ", "))
+	}
+	if err := pcs.checkProcess(pid, req.CreateTime); err != nil {
+		return result, err
+	}
+
+	if req.DryRun {
+		// signal 0 checks that process exists and we are allowed to signal it
+		if err := checkSignalPermission(pid); err != nil {
+			return result, err
+		}
+		result.Message = fmt.Sprintf("would send SIG%s to process %d", name, pid)
+		return result, nil
+	}
+
+	if err := sendSignal(pid, name); err != nil {
+		return result, err
+	}
+	result.Message = fmt.Sprintf("sent SIG%s to process %d", name, pid)
+	return result, nil
+}
+
+func (pcs *ProcessControlService) SetPriority(pid int32, req models.ProcessPriorityRequest) (models.ProcessActionResult, error) {
+	result := models.ProcessActionResult{PID: pid, Action: "priority", DryRun: req.DryRun}
+
+	if req.Nice == nil && req.IOClass == "" {
+		return result, fmt.Errorf("%w: nice or io_class is required", ErrInvalidAction)
+	}
+	if req.Nice != nil && (*req.Nice < -20 || *req.Nice > 19) {
+		return result, fmt.Errorf("%w: nice must be in -20..19", ErrInvalidAction)
+	}
+
+	ioClass, ioLevel := 0, 0
+	if req.IOClass != "" {
+		class, ok := ioClasses[req.IOClass]
+		if !ok {
+			return result, fmt.Errorf("%w: io_class must be realtime, best-effort or idle", ErrInvalidAction)
+		}
+		ioClass = class
+		if req.IOPriority != nil {
+			if *req.IOPriority < 0 || *req.IOPriority > 7 {
+				return result, fmt.Errorf("%w: io_priority must be in 0..7", ErrInvalidAction)
+			}
+			ioLevel = *req.IOPriority
+		} else if req.IOClass != "idle" {
+			ioLevel = 4 // kernel default level
+		}
+	} else if req.IOPriority != nil {
+		return result, fmt.Errorf("%w: io_priority needs io_class", ErrInvalidAction)
+	}
+
+	if err := pcs.checkProcess(pid, req.CreateTime); err != nil {
+		return result, err
+	}
+
+	var changes []string
+	if req.Nice != nil {
+		changes = append(changes, fmt.Sprintf("nice %d", *req.Nice))
+	}
+	if ioClass != 0 {
+		changes = append(changes, fmt.Sprintf("io %s/%d", req.IOClass, ioLevel))
+	}
+
+	if req.DryRun {
+		result.Message = fmt.Sprintf("would set %s for process %d", strings.Join(changes, ", "), pid)
+		return result, nil
+	}
+
+	if req.Nice != nil {
+		if err := setNice(pid, *req.Nice); err != nil {
+			return result, err
+		}
+	}
+	if ioClass != 0 {
+		if err := setIOPriority(pid, ioClass, ioLevel); err != nil {
+			return result, err
+		}
+	}
+	result.Message = fmt.Sprintf("set %s for process %d", strings.Join(changes, ", "), pid)
+	return result, nil
+}
+
+func (pcs *ProcessControlService) SetAffinity(pid int32, req models.ProcessAffinityRequest) (models.ProcessActionResult, error) {
+	result := models.ProcessActionResult{PID: pid, Action: "affinity", DryRun: req.DryRun}
+
+	if len(req.CPUs) == 0 {
+		return result, fmt.Errorf("%w: cpus must not be empty", ErrInvalidAction)
+	}
+	for _, cpu := range req.CPUs {
+		if cpu < 0 || cpu >= maxAffinityCPUs {
+			return result, fmt.Errorf("%w: cpu %d is out of range", ErrInvalidAction, cpu)
+		}
+	}
+	if err := pcs.checkProcess(pid, req.CreateTime); err != nil {
+		return result, err
+	}
+
+	if req.DryRun {
+		result.Message = fmt.Sprintf("would pin process %d to cpus %v", pid, req.CPUs)
+		return result, nil
+	}
+
+	if err := setAffinity(pid, req.CPUs); err != nil {
+		return result, err
+	}
+	result.Message = fmt.Sprintf("pinned process %d to cpus %v", pid, req.CPUs)
+	return result, nil
+}
+
+// checkProcess makes sure pid still belongs to the process client has seen
+func (pcs *ProcessControlService) checkProcess(pid int32, createTime int64) error {
+	if pid <= 0 {
+		return fmt.Errorf("%w: pid must be positive", ErrInvalidAction) // 0 and -1 mean process groups for kill
+	}
+	if int(pid) == os.Getpid() {
+		return fmt.Errorf("%w: SysPulse can't act on itself", ErrInvalidAction)
+	}
+	if createTime <= 0 {
+		return fmt.Errorf("%w: create_time is required", ErrInvalidAction)
+	}
+
+	p, err := process.NewProcess(pid)
+	if err != nil {
+		return ErrProcessNotFound
+	}
+	created, err := p.CreateTime()
+	if err != nil {
+		return ErrProcessNotFound
+	}
+	if created/1000 != createTime {
+		return ErrProcessReused
+	}
+	return nil
+}
+
+func isAllowedSignal(name string) bool {
+	for _, allowed := range allowedSignals {
+		if name == allowed {
+			return true
+		}
+	}
+	return false
+}
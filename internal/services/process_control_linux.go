@@ -0,0 +1,68 @@
+//go:build linux
+
+package services
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+const maxAffinityCPUs = 1024 // size of unix.CPUSet
+
+var signalsByName = map[string]syscall.Signal{
+	"TERM": syscall.SIGTERM,
+	"KILL": syscall.SIGKILL,
+	"HUP":  syscall.SIGHUP,
+	"STOP": syscall.SIGSTOP,
+	"CONT": syscall.SIGCONT,
+}
+
+func sendSignal(pid int32, name string) error {
+	return wrapErrno(syscall.Kill(int(pid), signalsByName[name]))
+}
+
+func checkSignalPermission(pid int32) error {
+	return wrapErrno(syscall.Kill(int(pid), 0))
+}
+
+func setNice(pid int32, nice int) error {
+	return wrapErrno(unix.Setpriority(unix.PRIO_PROCESS, int(pid), nice))
+}
+
+func setIOPriority(pid int32, class, level int) error {
+	const ioprioWhoProcess = 1
+	const ioprioClassShift = 13
+
+	_, _, errno := unix.Syscall(unix.SYS_IOPRIO_SET, ioprioWhoProcess, uintptr(pid), uintptr(class<<ioprioClassShift|level))
+	if errno != 0 {
+		return wrapErrno(errno)
+	}
+	return nil
+}
+
+func setAffinity(pid int32, cpus []int) error {
+	var set unix.CPUSet
+	set.Zero()
+	for _, cpu := range cpus {
+		set.Set(cpu)
+	}
+	return wrapErrno(unix.SchedSetaffinity(int(pid), &set))
+}
+
+func wrapErrno(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, syscall.ESRCH):
+		return ErrProcessNotFound
+	case errors.Is(err, syscall.EPERM), errors.Is(err, syscall.EACCES):
+		return fmt.Errorf("%w: %v", ErrNotPermitted, err)
+	case errors.Is(err, syscall.EINVAL):
+		return fmt.Errorf("%w: %v", ErrInvalidAction, err)
+	default:
+		return err
+	}
+}
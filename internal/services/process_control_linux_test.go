@@ -0,0 +1,204 @@
+//go:build linux
+
+package services
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+	"syspulse/internal/models"
+	"testing"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/process"
+	"golang.org/x/sys/unix"
+)
+
+// startSleeper runs a child that only waits to be acted on, it is killed when the test ends
+func startSleeper(t *testing.T) (*exec.Cmd, int32, int64) {
+	t.Helper()
+	cmd := exec.Command("sleep", "60")
+	if err := cmd.Start(); err != nil {
+		t.Skipf("can't start sleep: %v", err)
+	}
+	t.Cleanup(func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	})
+
+	pid := int32(cmd.Process.Pid)
+	p, err := process.NewProcess(pid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	created, err := p.CreateTime()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cmd, pid, created / 1000
+}
+
+// procStatField returns n-th field of /proc/[pid]/stat counted after comm: 0 is state, 16 is nice
+func procStatField(t *testing.T, pid int32, n int) string {
+	t.Helper()
+	data, err := os.ReadFile("/proc/" + strconv.Itoa(int(pid)) + "/stat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Fields(string(data[bytes.LastIndexByte(data, ')')+1:]))[n]
+}
+
+// waitForState polls the state letter, signals are delivered asynchronously
+func waitForState(t *testing.T, pid int32, want string) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		state := procStatField(t, pid, 0)
+		if state == want {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("process %d state = %s, want %s", pid, state, want)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestSignalStopContTerm(t *testing.T) {
+	cmd, pid, created := startSleeper(t)
+	pcs := NewProcessControlService()
+
+	if _, err := pcs.Signal(pid, models.ProcessSignalRequest{Signal: "STOP", CreateTime: created}); err != nil {
+		t.Fatal(err)
+	}
+	waitForState(t, pid, "T")
+
+	if _, err := pcs.Signal(pid, models.ProcessSignalRequest{Signal: "SIGCONT", CreateTime: created}); err != nil {
+		t.Fatal(err)
+	}
+	waitForState(t, pid, "S")
+
+	if _, err := pcs.Signal(pid, models.ProcessSignalRequest{Signal: "term", CreateTime: created}); err != nil {
+		t.Fatal(err)
+	}
+	err := cmd.Wait()
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.Sys().(syscall.WaitStatus).Signal() != syscall.SIGTERM {
+		t.Fatalf("sleep ended with %v, want SIGTERM", err)
+	}
+}
+
+func TestSignalOutsideAllowListIsRefused(t *testing.T) {
+	_, pid, created := startSleeper(t)
+	pcs := NewProcessControlService()
+
+	for _, signal := range []string{"USR1", "SIGINT", "9", ""} {
+		_, err := pcs.Signal(pid, models.ProcessSignalRequest{Signal: signal, CreateTime: created})
+		if !errors.Is(err, ErrInvalidAction) {
+			t.Errorf("signal %q: err = %v, want ErrInvalidAction", signal, err)
+		}
+	}
+	waitForState(t, pid, "S")
+}
+
+func TestWrongCreateTimeIsRejected(t *testing.T) {
+	_, pid, created := startSleeper(t)
+	pcs := NewProcessControlService()
+	nice := 10
+
+	if _, err := pcs.Signal(pid, models.ProcessSignalRequest{Signal: "KILL", CreateTime: created - 100}); !errors.Is(err, ErrProcessReused) {
+		t.Errorf("signal: err = %v, want ErrProcessReused", err)
+	}
+	if _, err := pcs.SetPriority(pid, models.ProcessPriorityRequest{Nice: &nice, CreateTime: created + 1}); !errors.Is(err, ErrProcessReused) {
+		t.Errorf("priority: err = %v, want ErrProcessReused", err)
+	}
+	if _, err := pcs.SetAffinity(pid, models.ProcessAffinityRequest{CPUs: []int{0}, CreateTime: 1}); !errors.Is(err, ErrProcessReused) {
+		t.Errorf("affinity: err = %v, want ErrProcessReused", err)
+	}
+	if _, err := pcs.Signal(pid, models.ProcessSignalRequest{Signal: "KILL"}); !errors.Is(err, ErrInvalidAction) {
+		t.Errorf("missing create_time: err = %v, want ErrInvalidAction", err)
+	}
+	waitForState(t, pid, "S")
+}
+
+func TestSetPriorityChangesNice(t *testing.T) {
+	_, pid, created := startSleeper(t)
+	pcs := NewProcessControlService()
+	nice := 10
+
+	if _, err := pcs.SetPriority(pid, models.ProcessPriorityRequest{Nice: &nice, CreateTime: created}); err != nil {
+		t.Fatal(err)
+	}
+	if got := procStatField(t, pid, 16); got != "10" {
+		t.Errorf("nice = %s, want 10", got)
+	}
+}
+
+func TestSetAffinityPinsProcess(t *testing.T) {
+	_, pid, created := startSleeper(t)
+	pcs := NewProcessControlService()
+
+	var allowed unix.CPUSet
+	if err := unix.SchedGetaffinity(int(pid), &allowed); err != nil {
+		t.Fatal(err)
+	}
+	cpu := -1
+	for i := 0; i < maxAffinityCPUs && cpu < 0; i++ {
+		if allowed.IsSet(i) {
+			cpu = i
+		}
+	}
+
+	if _, err := pcs.SetAffinity(pid, models.ProcessAffinityRequest{CPUs: []int{cpu}, CreateTime: created}); err != nil {
+		t.Fatal(err)
+	}
+	var set unix.CPUSet
+	if err := unix.SchedGetaffinity(int(pid), &set); err != nil {
+		t.Fatal(err)
+	}
+	if set.Count() != 1 || !set.IsSet(cpu) {
+		t.Errorf("affinity has %d cpus, want only cpu %d", set.Count(), cpu)
+	}
+}
+
+func TestDryRunLeavesProcessUntouched(t *testing.T) {
+	_, pid, created := startSleeper(t)
+	pcs := NewProcessControlService()
+	nice := 15
+	niceBefore := procStatField(t, pid, 16)
+
+	var before unix.CPUSet
+	if err := unix.SchedGetaffinity(int(pid), &before); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := pcs.Signal(pid, models.ProcessSignalRequest{Signal: "STOP", CreateTime: created, DryRun: true})
+	if err != nil || !result.DryRun || !strings.HasPrefix(result.Message, "would") {
+		t.Fatalf("dry-run signal = %+v, %v", result, err)
+	}
+	if _, err := pcs.SetPriority(pid, models.ProcessPriorityRequest{Nice: &nice, CreateTime: created, DryRun: true}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pcs.SetAffinity(pid, models.ProcessAffinityRequest{CPUs: []int{0}, CreateTime: created, DryRun: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(50 * time.Millisecond) // a stop would have been delivered by now
+	if state := procStatField(t, pid, 0); state != "S" {
+		t.Errorf("state after dry run = %s, want S", state)
+	}
+	if got := procStatField(t, pid, 16); got != niceBefore {
+		t.Errorf("nice after dry run = %s, want %s", got, niceBefore)
+	}
+	var after unix.CPUSet
+	if err := unix.SchedGetaffinity(int(pid), &after); err != nil {
+		t.Fatal(err)
+	}
+	if after != before {
+		t.Error("affinity changed by dry run")
+	}
+}
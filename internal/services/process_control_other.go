@@ -0,0 +1,33 @@
+//go:build !linux
+
+package services
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+)
+
+const maxAffinityCPUs = 1024
+
+var errControlUnsupported = fmt.Errorf("process control is not supported on %s: %w", runtime.GOOS, errors.ErrUnsupported)
+
+func sendSignal(pid int32, name string) error {
+	return errControlUnsupported
+}
+
+func checkSignalPermission(pid int32) error {
+	return errControlUnsupported
+}
+
+func setNice(pid int32, nice int) error {
+	return errControlUnsupported
+}
+
+func setIOPriority(pid int32, class, level int) error {
+	return errControlUnsupported
+}
+
+func setAffinity(pid int32, cpus []int) error {
+	return errControlUnsupported
+}
@@ -0,0 +1,413 @@
+package services
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syspulse/internal/models"
+	"time"
+)
+
+const (
+	DefaultFDListLimit = 100
+	ioSampleMaxAge     = time.Minute            // older sample is not used for rates
+	ioSampleMinAge     = 100 * time.Millisecond // younger sample gives too noisy rates
+)
+
+type ProcessDetailsOptions struct {
+	FDLimit     int  // how many descriptors to list
+	Environment bool // include environment, only for admin
+}
+
+// ioSample remembers io counters of process from previous details request, so rates need no waiting next time
+type ioSample struct {
+	createTime int64
+	io         models.ProcessIO
+	sampledAt  time.Time
+}
+
+type ioSampleStore struct {
+	mu      sync.Mutex
+	samples map[int32]ioSample
+}
+
+// ProcessDetails reads everything we know about one process from procfs. Usage comes from the last
+// collection, a process started since or a pid reused since is read from procfs instead
+func (ms *MetricsService) ProcessDetails(pid int32, opts ProcessDetailsOptions) (models.ProcessDetails, error) {
+	latest := ms.GetLatestMetrics()
+	var info *models.ProcessInfo
+	for _, p := range latest.Processes {
+		if p.PID == pid {
+			info = &p
+			break
+		}
+	}
+
+	details := models.ProcessDetails{
+		Errors:    make(map[string]string),
+		Timestamp: time.Now(),
+	}
+	if !ms.processes.useProcfs {
+		if info == nil {
+			return models.ProcessDetails{}, ErrProcessNotFound
+		}
+		details.Process, details.Cgroup = *info, info.Cgroup
+		details.Errors["procfs"] = "process details need procfs"
+		return details, nil
+	}
+
+	dir := filepath.Join(ms.processes.procRoot, strconv.Itoa(int(pid)))
+	sample, err := ms.processes.sample(pid)
+	switch {
+	case err == nil && (info == nil || info.CreateTime != sample.CreateTime/1000):
+		live := ms.processes.liveProcess(sample, latest.Memory.Total)
+		info = &live
+	case err != nil:
+		if _, statErr := os.Stat(dir); os.IsNotExist(statErr) {
+			return models.ProcessDetails{}, ErrProcessNotFound
+		}
+		details.Errors["stat"] = describeProcError(err)
+		if info == nil {
+			info = &models.ProcessInfo{PID: pid}
+		}
+	}
+	details.Process, details.Cgroup = *info, info.Cgroup
+
+	if exe, err := os.Readlink(filepath.Join(dir, "exe")); err == nil {
+		details.Exe = exe
+	} else {
+		details.Errors["exe"] = describeProcError(err)
+	}
+	if cwd, err := os.Readlink(filepath.Join(dir, "cwd")); err == nil {
+		details.Cwd = cwd
+	} else {
+		details.Errors["cwd"] = describeProcError(err)
+	}
+
+	if io, err := ms.processIO(dir, pid, info.CreateTime); err == nil {
+		details.IO = &io
+	} else {
+		details.Errors["io"] = describeProcError(err)
+	}
+
+	if opts.FDLimit <= 0 {
+		opts.FDLimit = DefaultFDListLimit
+	}
+	fds, err := readProcFDs(dir, opts.FDLimit)
+	if err != nil {
+		details.Errors["fds"] = describeProcError(err)
+	}
+	if err == nil || fds.Count > 0 { // the count is known even when targets can't be read
+		details.FDs = &fds
+	}
+
+	if sockets, err := processSockets(dir); err == nil {
+		details.Sockets = &sockets
+	} else {
+		details.Errors["sockets"] = describeProcError(err)
+	}
+
+	if maps, err := readSmapsSummary(filepath.Join(dir, "smaps")); err == nil {
+		details.MemoryMaps = maps
+	} else {
+		details.Errors["memory_maps"] = describeProcError(err)
+	}
+
+	if opts.Environment {
+		if env, err := readNulSeparated(filepath.Join(dir, "environ")); err == nil {
+			details.Environment = env
+		} else {
+			details.Errors["environment"] = describeProcError(err)
+		}
+	}
+
+	if len(details.Errors) == 0 {
+		details.Errors = nil
+	}
+	return details, nil
+}
+
+// processIO reads io counters and computes storage rates against previous sample of the same process,
+// the first request has totals only
+func (ms *MetricsService) processIO(dir string, pid int32, createTime int64) (models.ProcessIO, error) {
+	current, err := readProcIO(filepath.Join(dir, "io"))
+	if err != nil {
+		return current, err
+	}
+	now := time.Now()
+
+	ms.ioSamples.mu.Lock()
+	defer ms.ioSamples.mu.Unlock()
+
+	prev, ok := ms.ioSamples.samples[pid]
+	age := now.Sub(prev.sampledAt)
+	if ok && prev.createTime == createTime && age < ioSampleMinAge {
+		return current, nil // keep the older sample as baseline for the next request
+	}
+	if ok && prev.createTime == createTime && age <= ioSampleMaxAge {
+		elapsed := age.Seconds()
+		readRate := float64(counterDelta(prev.io.ReadBytes, current.ReadBytes)) / elapsed
+		writeRate := float64(counterDelta(prev.io.WriteBytes, current.WriteBytes)) / elapsed
+		current.ReadRate = &readRate
+		current.WriteRate = &writeRate
+	}
+
+	ms.ioSamples.samples[pid] = ioSample{createTime: createTime, io: current, sampledAt: now}
+	for samplePID, sample := range ms.ioSamples.samples {
+		if now.Sub(sample.sampledAt) > ioSampleMaxAge {
+			delete(ms.ioSamples.samples, samplePID)
+		}
+	}
+	return current, nil
+}
+
+func readProcIO(path string) (models.ProcessIO, error) {
+	var io models.ProcessIO
+	values, err := readKeyValueFile(path)
+	if err != nil {
+		return io, err
+	}
+	io.ReadChars = values["rchar"]
+	io.WriteChars = values["wchar"]
+	io.ReadOps = values["syscr"]
+	io.WriteOps = values["syscw"]
+	io.ReadBytes = values["read_bytes"]
+	io.WriteBytes = values["write_bytes"]
+	return io, nil
+}
+
+// readProcFDs lists up to limit descriptors. When a target can't be read, fds has the count and
+// the targets read so far
+func readProcFDs(dir string, limit int) (models.ProcessFDs, error) {
+	var fds models.ProcessFDs
+	entries, err := os.ReadDir(filepath.Join(dir, "fd"))
+	if err != nil {
+		return fds, err
+	}
+
+	numbers := make([]int, 0, len(entries))
+	for _, entry := range entries {
+		if fd, err := strconv.Atoi(entry.Name()); err == nil {
+			numbers = append(numbers, fd)
+		}
+	}
+	sort.Ints(numbers)
+
+	fds.Count = len(numbers)
+	fds.Limit = readOpenFilesLimit(filepath.Join(dir, "limits"))
+	fds.Files = make([]models.ProcessFD, 0, min(limit, len(numbers)))
+	for _, fd := range numbers {
+		if len(fds.Files) == limit {
+			fds.Truncated = true
+			break
+		}
+		target, err := os.Readlink(filepath.Join(dir, "fd", strconv.Itoa(fd)))
+		if os.IsNotExist(err) {
+			continue // closed while we were reading
+		}
+		if err != nil {
+			return fds, err
+		}
+		fds.Files = append(fds.Files, models.ProcessFD{FD: fd, Target: target})
+	}
+	return fds, nil
+}
+
+// readOpenFilesLimit returns soft limit from "Max open files  1024  4096  files" line
+func readOpenFilesLimit(path string) uint64 {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if rest, ok := strings.CutPrefix(line, "Max open files"); ok {
+			if fields := strings.Fields(rest); len(fields) > 0 {
+				limit, _ := strconv.ParseUint(fields[0], 10, 64) // "unlimited" gives 0
+				return limit
+			}
+		}
+	}
+	return 0
+}
+
+// processSockets reads socket tables of the network namespace the process lives in
+// and keeps sockets its descriptors point to
+func processSockets(dir string) (models.ProcessSockets, error) {
+	sockets := models.ProcessSockets{
+		Listening:   []models.ProcessSocket{},
+		Established: []models.ProcessSocket{},
+	}
+
+	inodes, err := socketInodes(filepath.Join(dir, "fd"))
+	if err != nil {
+		return sockets, err
+	}
+	entries, err := readSockets(dir)
+	if err != nil {
+		return sockets, err
+	}
+
+	for _, entry := range entries {
+		if !inodes[entry.inode] {
+			continue
+		}
+		socket := models.ProcessSocket{
+			Protocol:   entry.protocol,
+			LocalAddr:  entry.localAddr,
+			LocalPort:  entry.localPort,
+			RemoteAddr: entry.remoteAddr,
+			RemotePort: entry.remotePort,
+			Status:     entry.state,
+		}
+		switch {
+		case entry.listening():
+			sockets.Listening = append(sockets.Listening, socket) // bound udp sockets count as listening
+		case entry.state == "ESTABLISHED" || entry.state == "NONE":
+			sockets.Established = append(sockets.Established, socket)
+		default:
+			sockets.Other++
+		}
+	}
+	return sockets, nil
+}
+
+// readSmapsSummary sums smaps entries by mapping type
+func readSmapsSummary(path string) ([]models.MemoryMapSummary, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	summaries := make(map[string]*models.MemoryMapSummary)
+	var current *models.MemoryMapSummary
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		if !strings.HasSuffix(fields[0], ":") { // header of a mapping: address perms offset dev inode [path]
+			mapType := mappingType(fields)
+			current = summaries[mapType]
+			if current == nil {
+				current = &models.MemoryMapSummary{Type: mapType}
+				summaries[mapType] = current
+			}
+			current.Mappings++
+			continue
+		}
+
+		if current == nil || len(fields) < 2 {
+			continue
+		}
+		kb, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		switch fields[0] {
+		case "Size:":
+			current.Size += kb * 1024
+		case "Rss:":
+			current.RSS += kb * 1024
+		case "Pss:":
+			current.PSS += kb * 1024
+		case "Swap:":
+			current.Swap += kb * 1024
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	result := make([]models.MemoryMapSummary, 0, len(summaries))
+	for _, summary := range summaries {
+		result = append(result, *summary)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].RSS > result[j].RSS })
+	return result, nil
+}
+
+func mappingType(header []string) string {
+	if len(header) < 6 {
+		return "anon"
+	}
+	path := strings.Join(header[5:], " ")
+	switch {
+	case path == "[heap]":
+		return "heap"
+	case strings.HasPrefix(path, "[stack"):
+		return "stack"
+	case path == "[vdso]" || path == "[vvar]" || path == "[vsyscall]":
+		return "kernel"
+	case strings.HasPrefix(path, "[anon"):
+		return "anon"
+	case strings.HasPrefix(path, "/dev/shm/") || strings.HasPrefix(path, "/SYSV") || strings.HasPrefix(path, "/memfd:"):
+		return "shmem"
+	case strings.HasPrefix(path, "/"):
+		return "file"
+	default:
+		return "anon"
+	}
+}
+
+func readNulSeparated(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var values []string
+	for _, value := range strings.Split(string(data), "\x00") {
+		if value != "" {
+			values = append(values, value)
+		}
+	}
+	return values, nil
+}
+
+// readKeyValueFile parses "key: value" and "key value" lines with numeric values, like /proc/[pid]/io
+func readKeyValueFile(path string) (map[string]uint64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]uint64)
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		if value, err := strconv.ParseUint(fields[1], 10, 64); err == nil {
+			values[strings.TrimSuffix(fields[0], ":")] = value
+		}
+	}
+	return values, nil
+}
+
+// counterDelta returns growth of a counter, counter that went back was reset and counts from zero
+func counterDelta(prev, current uint64) uint64 {
+	if current < prev {
+		return current
+	}
+	return current - prev
+}
+
+func describeProcError(err error) string {
+	switch {
+	case os.IsPermission(err):
+		return "permission denied"
+	case os.IsNotExist(err):
+		return "not available"
+	default:
+		return fmt.Sprint(err)
+	}
+}
@@ -0,0 +1,119 @@
+package services
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syspulse/internal/models"
+	"testing"
+)
+
+// newTestDetailsService returns a service whose last collection saw processes, procfs is fp
+func newTestDetailsService(fp *fakeProcfs, processes ...models.ProcessInfo) *MetricsService {
+	return &MetricsService{
+		processes: newProcessCache(fp.root, false, 1),
+		ioSamples: ioSampleStore{samples: make(map[int32]ioSample)},
+		latest:    &models.SystemMetrics{Processes: processes, Memory: models.MemInfo{Total: 1 << 30}},
+	}
+}
+
+func addFDs(t *testing.T, fp *fakeProcfs, pid string, targets ...string) {
+	t.Helper()
+	dir := filepath.Join(fp.root, pid, "fd")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for fd, target := range targets {
+		if err := os.Symlink(target, filepath.Join(dir, strconv.Itoa(fd))); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestProcessDetailsCreateTime(t *testing.T) {
+	fp := newFakeProcfs(t, 100)
+	fp.process(100, "nginx", 0, 0, 500) // started 5s after boot
+	fp.process(200, "worker", 0, 0, 900)
+	startedAt := int64(fakeBootTime + 5)
+
+	ms := newTestDetailsService(fp,
+		models.ProcessInfo{PID: 100, Process: "nginx", CPUPercent: 12, CreateTime: startedAt},
+		models.ProcessInfo{PID: 300, Process: "gone", CreateTime: startedAt},
+	)
+
+	details, err := ms.ProcessDetails(100, ProcessDetailsOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if details.Process.CPUPercent != 12 {
+		t.Errorf("known process cpu = %.1f, want the collected 12", details.Process.CPUPercent)
+	}
+
+	// started after the last collection
+	details, err = ms.ProcessDetails(200, ProcessDetailsOptions{})
+	if err != nil {
+		t.Fatalf("process started after the collection: %v", err)
+	}
+	if p := details.Process; p.Process != "worker" || p.CommandLine != "/usr/bin/worker --flag" || p.User != "root" || p.CreateTime != fakeBootTime+9 {
+		t.Errorf("live process = %+v", p)
+	}
+	if details.Cgroup != "/system.slice/worker.service" || details.Process.MemoryRSS == 0 || details.Process.MemoryPercent == 0 {
+		t.Errorf("live process cgroup %q, rss %d, memory %.3f%%", details.Cgroup, details.Process.MemoryRSS, details.Process.MemoryPercent)
+	}
+
+	// pid 100 reused by another process since the collection
+	ms.latest.Processes[0].CreateTime = startedAt - 60
+	ms.latest.Processes[0].Process = "old"
+	details, err = ms.ProcessDetails(100, ProcessDetailsOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := details.Process; p.Process != "nginx" || p.CreateTime != startedAt || p.CPUPercent != 0 {
+		t.Errorf("reused pid = %+v, want the new process", p)
+	}
+
+	if _, err := ms.ProcessDetails(300, ProcessDetailsOptions{}); !errors.Is(err, ErrProcessNotFound) {
+		t.Errorf("exited process err = %v, want not found", err)
+	}
+}
+
+func TestReadProcFDs(t *testing.T) {
+	fp := newFakeProcfs(t, 100)
+	fp.process(100, "nginx", 0, 0, 500)
+	addFDs(t, fp, "100", "/dev/null", "socket:[1234]", "/var/log/nginx/access.log", "pipe:[99]", "anon_inode:[eventfd]")
+	fp.write("100/limits", "Limit                     Soft Limit           Hard Limit           Units\n"+
+		"Max open files            1024                 524288               files\n")
+	dir := filepath.Join(fp.root, "100")
+
+	fds, err := readProcFDs(dir, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fds.Count != 5 || len(fds.Files) != 3 || !fds.Truncated || fds.Limit != 1024 {
+		t.Errorf("limited fds = %+v", fds)
+	}
+	if fds.Files[2].FD != 2 || fds.Files[2].Target != "/var/log/nginx/access.log" {
+		t.Errorf("fd 2 = %+v", fds.Files[2])
+	}
+
+	if fds, err := readProcFDs(dir, 5); err != nil || len(fds.Files) != 5 || fds.Truncated {
+		t.Errorf("all fds = %+v, %v, want 5 without truncation", fds, err)
+	}
+
+	// a target that can't be read is an error, not a closed descriptor
+	fp.write("100/fd/7", "")
+	fds, err = readProcFDs(dir, 100)
+	if err == nil || fds.Count != 6 || len(fds.Files) != 5 || fds.Truncated {
+		t.Errorf("unreadable fd = %+v, %v, want error with 6 counted and 5 listed", fds, err)
+	}
+
+	ms := newTestDetailsService(fp, models.ProcessInfo{PID: 100, Process: "nginx", CreateTime: fakeBootTime + 5})
+	details, err := ms.ProcessDetails(100, ProcessDetailsOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if details.Errors["fds"] == "" || details.FDs == nil || details.FDs.Count != 6 {
+		t.Errorf("details fds %+v, error %q, want the count with an error", details.FDs, details.Errors["fds"])
+	}
+}
@@ -0,0 +1,161 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"syspulse/internal/models"
+	"time"
+)
+
+// processGroup is a compiled group definition with its state between collections
+type processGroup struct {
+	config       models.ProcessGroupConfig
+	process      *regexp.Regexp
+	cmdline      *regexp.Regexp
+	restarts     int
+	pendingExits int // exits of the previous collection not followed by a start, they expire after one collection
+}
+
+// processGroups aggregates processes of watched groups on every collection
+type processGroups struct {
+	groups []*processGroup
+}
+
+// ParseProcessGroups reads json list of group definitions, as given in SYS_PULSE_PROCESS_GROUPS
+func ParseProcessGroups(data string) ([]models.ProcessGroupConfig, error) {
+	if strings.TrimSpace(data) == "" {
+		return nil, nil
+	}
+
+	var configs []models.ProcessGroupConfig
+	if err := json.Unmarshal([]byte(data), &configs); err != nil {
+		return nil, err
+	}
+	if _, err := newProcessGroups(configs); err != nil {
+		return nil, err
+	}
+	return configs, nil
+}
+
+func newProcessGroups(configs []models.ProcessGroupConfig) (*processGroups, error) {
+	pg := &processGroups{}
+	names := make(map[string]bool)
+	for _, config := range configs {
+		if config.Name == "" {
+			return nil, fmt.Errorf("process group needs a name")
+		}
+		if names[config.Name] {
+			return nil, fmt.Errorf("duplicate process group %q", config.Name)
+		}
+		names[config.Name] = true
+		if config.Process == "" && config.User == "" && config.Cmdline == "" && config.Cgroup == "" {
+			return nil, fmt.Errorf("process group %q matches nothing, set process, user, cmdline or cgroup", config.Name)
+		}
+
+		group := &processGroup{config: config}
+		var err error
+		if config.Process != "" {
+			if group.process, err = regexp.Compile(config.Process); err != nil {
+				return nil, fmt.Errorf("process group %q: process: %w", config.Name, err)
+			}
+		}
+		if config.Cmdline != "" {
+			if group.cmdline, err = regexp.Compile(config.Cmdline); err != nil {
+				return nil, fmt.Errorf("process group %q: cmdline: %w", config.Name, err)
+			}
+		}
+		pg.groups = append(pg.groups, group)
+	}
+	return pg, nil
+}
+
+func (g *processGroup) matches(name, user, cmdline, cgroup string) bool {
+	if g.process != nil && !g.process.MatchString(name) {
+		return false
+	}
+	if g.config.User != "" && g.config.User != user {
+		return false
+	}
+	if g.cmdline != nil && !g.cmdline.MatchString(cmdline) {
+		return false
+	}
+	if g.config.Cgroup != "" && !strings.HasPrefix(cgroup, g.config.Cgroup) {
+		return false
+	}
+	return true
+}
+
+// update aggregates processes by group and counts restarts from process events, group_restarted events are returned
+func (pg *processGroups) update(processes []models.ProcessInfo, events []models.Event, now time.Time) ([]models.ProcessGroup, []models.Event) {
+	if pg == nil || len(pg.groups) == 0 {
+		return nil, nil
+	}
+
+	var groupEvents []models.Event
+	result := make([]models.ProcessGroup, 0, len(pg.groups))
+
+	for _, group := range pg.groups {
+		// a start pairs with an exit of this or the previous collection, so a process replaced within one
+		// collection or across two counts as restart, and a scale-down followed much later by a scale-up does not
+		exits, carried := 0, group.pendingExits
+		for _, event := range events {
+			p := event.Process
+			if event.Type == models.EventProcessExited && p != nil && group.matches(p.Name, p.User, p.CommandLine, p.Cgroup) {
+				exits++
+			}
+		}
+		for _, event := range events {
+			p := event.Process
+			if event.Type != models.EventProcessStarted || p == nil || !group.matches(p.Name, p.User, p.CommandLine, p.Cgroup) {
+				continue
+			}
+			switch {
+			case carried > 0:
+				carried--
+			case exits > 0:
+				exits--
+			default:
+				continue
+			}
+			group.restarts++
+			groupEvents = append(groupEvents, models.Event{
+				ID:        fmt.Sprintf("%s-%s-%d-%d", models.EventGroupRestarted, group.config.Name, p.PID, p.CreateTime),
+				Type:      models.EventGroupRestarted,
+				Timestamp: now,
+				Message:   fmt.Sprintf("process group %s restarted, new process %s (%d)", group.config.Name, p.Name, p.PID),
+				Process:   p,
+				Group:     group.config.Name,
+			})
+		}
+		group.pendingExits = exits
+
+		stats := models.ProcessGroup{Name: group.config.Name, Status: "down", Restarts: group.restarts, PIDs: []int32{}}
+		var oldest int64
+		for _, p := range processes {
+			if !group.matches(p.Process, p.User, p.CommandLine, p.Cgroup) {
+				continue
+			}
+			stats.Processes++
+			stats.Threads += max(p.Threads, 0)
+			stats.CPUPercent += p.CPUPercent
+			stats.MemoryRSS += p.MemoryRSS
+			stats.MemoryPercent += p.MemoryPercent
+			stats.PIDs = append(stats.PIDs, p.PID)
+			if oldest == 0 || (p.CreateTime > 0 && p.CreateTime < oldest) {
+				oldest = p.CreateTime
+			}
+		}
+		if stats.Processes > 0 {
+			stats.Status = "up"
+			if oldest > 0 {
+				stats.Uptime = max(now.Sub(time.Unix(oldest, 0)).Seconds(), 0)
+			}
+		}
+		sort.Slice(stats.PIDs, func(i, j int) bool { return stats.PIDs[i] < stats.PIDs[j] })
+		result = append(result, stats)
+	}
+	return result, groupEvents
+}
@@ -0,0 +1,45 @@
+package services
+
+import (
+	"syspulse/internal/models"
+	"testing"
+	"time"
+)
+
+func groupEvent(eventType string, pid int32) models.Event {
+	return models.Event{Type: eventType, Process: &models.ProcessEventInfo{PID: pid, Name: "nginx", CreateTime: int64(pid)}}
+}
+
+func TestProcessGroupRestarts(t *testing.T) {
+	pg, err := newProcessGroups([]models.ProcessGroupConfig{{Name: "web", Process: "^nginx$"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	restarts := func(events ...models.Event) int {
+		_, groupEvents := pg.update(nil, events, now)
+		return len(groupEvents)
+	}
+
+	if got := restarts(groupEvent(models.EventProcessExited, 1), groupEvent(models.EventProcessStarted, 2)); got != 1 {
+		t.Errorf("replaced within one collection: %d restarts, want 1", got)
+	}
+
+	// exit in one collection, start in the next one
+	if got := restarts(groupEvent(models.EventProcessExited, 2)); got != 0 {
+		t.Errorf("exit alone: %d restarts, want 0", got)
+	}
+	if got := restarts(groupEvent(models.EventProcessStarted, 3)); got != 1 {
+		t.Errorf("start after exit of previous collection: %d restarts, want 1", got)
+	}
+
+	// scale down, later scale up is not a restart
+	restarts(groupEvent(models.EventProcessExited, 3), groupEvent(models.EventProcessExited, 4))
+	restarts()
+	if got := restarts(groupEvent(models.EventProcessStarted, 5), groupEvent(models.EventProcessStarted, 6)); got != 0 {
+		t.Errorf("start two collections after exits: %d restarts, want 0", got)
+	}
+	if pg.groups[0].restarts != 2 {
+		t.Errorf("total restarts = %d, want 2", pg.groups[0].restarts)
+	}
+}
@@ -0,0 +1,216 @@
+package services
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"syspulse/internal/models"
+)
+
+const (
+	DefaultProcessLimit = 50
+	MaxProcessLimit     = 1000
+)
+
+// sortable fields of models.ProcessInfo, keys are json names
+var processSortFields = map[string]func(p models.ProcessInfo) processSortKey{
+	"pid":            func(p models.ProcessInfo) processSortKey { return processSortKey{Num: float64(p.PID)} },
+	"process":        func(p models.ProcessInfo) processSortKey { return processSortKey{Str: strings.ToLower(p.Process)} },
+	"cpu_percent":    func(p models.ProcessInfo) processSortKey { return processSortKey{Num: p.CPUPercent} },
+	"memory_percent": func(p models.ProcessInfo) processSortKey { return processSortKey{Num: p.MemoryPercent} },
+	"memory_rss":     func(p models.ProcessInfo) processSortKey { return processSortKey{Num: float64(p.MemoryRSS)} },
+	"threads":        func(p models.ProcessInfo) processSortKey { return processSortKey{Num: float64(p.Threads)} },
+	"createtime":     func(p models.ProcessInfo) processSortKey { return processSortKey{Num: float64(p.CreateTime)} },
+	"user":           func(p models.ProcessInfo) processSortKey { return processSortKey{Str: p.User} },
+	"status":         func(p models.ProcessInfo) processSortKey { return processSortKey{Str: p.Status} },
+}
+
+type ProcessQuery struct {
+	Sort      string         // json name of field, cpu_percent by default
+	Desc      bool           // descending order
+	Limit     int            // page size
+	Cursor    string         // next_cursor from previous page
+	User      string         // exact user name
+	Name      string         // exact process name
+	NameMatch *regexp.Regexp // name~= filter
+	Status    string         // status word or letter, sleep or S, etc.
+}
+
+type processSortKey struct {
+	Num float64 `json:"n,omitempty"`
+	Str string  `json:"s,omitempty"`
+}
+
+// cursor points to the last process of previous page, so pages stay correct when list changes between requests
+type processCursor struct {
+	Sort string         `json:"f"`
+	Desc bool           `json:"d,omitempty"`
+	Key  processSortKey `json:"k"`
+	PID  int32          `json:"p"`
+}
+
+type ProcessQueryError struct {
+	Message string
+}
+
+func (e *ProcessQueryError) Error() string {
+	return e.Message
+}
+
+func IsProcessSortField(field string) bool {
+	_, ok := processSortFields[field]
+	return ok
+}
+
+// QueryProcesses filters, sorts and pages processes from latest snapshot
+func (ms *MetricsService) QueryProcesses(query ProcessQuery) (models.ProcessList, error) {
+	return FilterProcesses(ms.GetLatestMetrics().Processes, query)
+}
+
+func FilterProcesses(processes []models.ProcessInfo, query ProcessQuery) (models.ProcessList, error) {
+	if query.Sort == "" {
+		query.Sort = "cpu_percent"
+	}
+	keyOf, ok := processSortFields[query.Sort]
+	if !ok {
+		return models.ProcessList{}, &ProcessQueryError{Message: fmt.Sprintf("unknown sort field %q", query.Sort)}
+	}
+	if query.Limit <= 0 {
+		query.Limit = DefaultProcessLimit
+	}
+	if query.Limit > MaxProcessLimit {
+		query.Limit = MaxProcessLimit
+	}
+
+	matched := make([]models.ProcessInfo, 0, len(processes))
+	for _, p := range processes {
+		if query.User != "" && p.User != query.User {
+			continue
+		}
+		if query.Name != "" && p.Process != query.Name {
+			continue
+		}
+		if query.NameMatch != nil && !query.NameMatch.MatchString(p.Process) {
+			continue
+		}
+		if query.Status != "" && !processStatusMatches(p.Status, query.Status) {
+			continue
+		}
+		matched = append(matched, p)
+	}
+
+	before := func(a models.ProcessInfo, aKey processSortKey, b models.ProcessInfo, bKey processSortKey) bool {
+		if cmp := compareSortKeys(aKey, bKey); cmp != 0 {
+			if query.Desc {
+				return cmp > 0
+			}
+			return cmp < 0
+		}
+		return a.PID < b.PID // pid keeps order stable for equal values
+	}
+
+	sort.SliceStable(matched, func(i, j int) bool {
+		return before(matched[i], keyOf(matched[i]), matched[j], keyOf(matched[j]))
+	})
+
+	start := 0
+	if query.Cursor != "" {
+		cursor, err := decodeProcessCursor(query.Cursor)
+		if err != nil {
+			return models.ProcessList{}, err
+		}
+		if cursor.Sort != query.Sort || cursor.Desc != query.Desc {
+			return models.ProcessList{}, &ProcessQueryError{Message: "cursor was issued for another sort order"}
+		}
+		last := models.ProcessInfo{PID: cursor.PID}
+		start = sort.Search(len(matched), func(i int) bool {
+			return before(last, cursor.Key, matched[i], keyOf(matched[i]))
+		})
+	}
+
+	end := min(start+query.Limit, len(matched))
+	list := models.ProcessList{
+		Processes: matched[start:end],
+		Total:     len(matched),
+	}
+	if end < len(matched) {
+		last := matched[end-1]
+		list.NextCursor = encodeProcessCursor(processCursor{
+			Sort: query.Sort,
+			Desc: query.Desc,
+			Key:  keyOf(last),
+			PID:  last.PID,
+		})
+	}
+
+	return list, nil
+}
+
+// status letters as in ps, gopsutil reports full words
+var processStatusLetters = map[string]string{
+	"running": "R", "sleep": "S", "blocked": "D", "stop": "T", "idle": "I",
+	"zombie": "Z", "wait": "W", "lock": "L", "dead": "X",
+}
+
+func processStatusMatches(status, filter string) bool {
+	if strings.EqualFold(status, filter) {
+		return true
+	}
+	letter, ok := processStatusLetters[status]
+	return ok && strings.EqualFold(letter, filter)
+}
+
+func compareSortKeys(a, b processSortKey) int {
+	switch {
+	case a.Num < b.Num:
+		return -1
+	case a.Num > b.Num:
+		return 1
+	}
+	return strings.Compare(a.Str, b.Str)
+}
+
+func encodeProcessCursor(cursor processCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeProcessCursor(value string) (processCursor, error) {
+	var cursor processCursor
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return cursor, &ProcessQueryError{Message: "malformed cursor"}
+	}
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return cursor, &ProcessQueryError{Message: "malformed cursor"}
+	}
+	return cursor, nil
+}
+
+// TopProcesses keeps top n processes by cpu and top n by memory, used to make websocket messages small
+func TopProcesses(processes []models.ProcessInfo, n int) []models.ProcessInfo {
+	if n <= 0 || len(processes) <= n {
+		return processes
+	}
+
+	byCPU := append([]models.ProcessInfo(nil), processes...)
+	sort.SliceStable(byCPU, func(i, j int) bool { return byCPU[i].CPUPercent > byCPU[j].CPUPercent })
+
+	byMemory := append([]models.ProcessInfo(nil), processes...)
+	sort.SliceStable(byMemory, func(i, j int) bool { return byMemory[i].MemoryPercent > byMemory[j].MemoryPercent })
+
+	top := make([]models.ProcessInfo, 0, 2*n)
+	seen := make(map[int32]bool, 2*n)
+	for _, list := range [][]models.ProcessInfo{byCPU[:n], byMemory[:n]} {
+		for _, p := range list {
+			if !seen[p.PID] {
+				seen[p.PID] = true
+				top = append(top, p)
+			}
+		}
+	}
+	return top
+}
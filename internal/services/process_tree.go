@@ -0,0 +1,180 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"syspulse/internal/models"
+)
+
+// ways to collapse process tree
+const (
+	GroupByNone   = ""
+	GroupByName   = "name"   // same-named children are folded into parent, e.g. all chrome processes
+	GroupByUnit   = "unit"   // flat list of systemd units
+	GroupByCgroup = "cgroup" // flat list of cgroups
+)
+
+// ProcessTree builds tree of processes from latest snapshot
+func (ms *MetricsService) ProcessTree(groupBy string) (models.ProcessTree, error) {
+	snapshot := ms.GetLatestMetrics()
+	tree, err := BuildProcessTree(snapshot.Processes, groupBy)
+	tree.Timestamp = snapshot.TimeStamp
+	return tree, err
+}
+
+func BuildProcessTree(processes []models.ProcessInfo, groupBy string) (models.ProcessTree, error) {
+	tree := models.ProcessTree{GroupBy: groupBy}
+
+	switch groupBy {
+	case GroupByNone, GroupByName:
+		tree.Roots = buildPIDTree(processes, groupBy == GroupByName)
+	case GroupByUnit:
+		tree.Roots = groupProcesses(processes, func(p models.ProcessInfo) string { return systemdUnit(p.Cgroup) })
+	case GroupByCgroup:
+		tree.Roots = groupProcesses(processes, func(p models.ProcessInfo) string { return p.Cgroup })
+	default:
+		return tree, fmt.Errorf("unknown group_by %q, use name, unit or cgroup", groupBy)
+	}
+
+	for _, root := range tree.Roots {
+		addTotals(&tree.Total, root.Total)
+	}
+	return tree, nil
+}
+
+func buildPIDTree(processes []models.ProcessInfo, foldByName bool) []*models.ProcessNode {
+	nodes := make(map[int32]*models.ProcessNode, len(processes))
+	for i := range processes {
+		p := processes[i]
+		nodes[p.PID] = &models.ProcessNode{Name: p.Process, Process: &p}
+	}
+
+	var roots []*models.ProcessNode
+	for _, p := range processes {
+		node := nodes[p.PID]
+		parent, ok := nodes[p.PPID]
+		if !ok || p.PPID == p.PID { // parent is gone or lives outside of our pid namespace
+			roots = append(roots, node)
+			continue
+		}
+		parent.Children = append(parent.Children, node)
+	}
+
+	for _, root := range roots {
+		computeTotals(root)
+		if foldByName {
+			foldSameName(root) // folding keeps the set of processes in subtree, so totals stay valid
+		}
+	}
+	sortNodes(roots)
+	return roots
+}
+
+// foldSameName moves children of same-named child to node, so "chrome" node covers all of chrome
+func foldSameName(node *models.ProcessNode) {
+	var children []*models.ProcessNode
+	queue := node.Children
+	for len(queue) > 0 {
+		child := queue[0]
+		queue = queue[1:]
+		if child.Name == node.Name {
+			node.Merged = append(node.Merged, child.Process.PID)
+			queue = append(queue, child.Children...)
+			continue
+		}
+		children = append(children, child)
+	}
+	node.Children = children
+	sortNodes(node.Children)
+
+	for _, child := range node.Children {
+		foldSameName(child)
+	}
+}
+
+// computeTotals sums usage of the subtree
+func computeTotals(node *models.ProcessNode) models.ProcessTotals {
+	total := models.ProcessTotals{}
+	if node.Process != nil {
+		addProcess(&total, *node.Process)
+	}
+	for _, child := range node.Children {
+		addTotals(&total, computeTotals(child))
+	}
+	node.Total = total
+	sortNodes(node.Children)
+	return total
+}
+
+// groupProcesses makes flat groups, processes themselves are children of group
+func groupProcesses(processes []models.ProcessInfo, keyOf func(p models.ProcessInfo) string) []*models.ProcessNode {
+	groups := make(map[string]*models.ProcessNode)
+	var roots []*models.ProcessNode
+
+	for i := range processes {
+		p := processes[i]
+		key := keyOf(p)
+		if key == "" {
+			key = "unknown"
+		}
+
+		group, ok := groups[key]
+		if !ok {
+			group = &models.ProcessNode{Name: key}
+			groups[key] = group
+			roots = append(roots, group)
+		}
+
+		leaf := &models.ProcessNode{Name: p.Process, Process: &p}
+		addProcess(&leaf.Total, p)
+		group.Children = append(group.Children, leaf)
+		addProcess(&group.Total, p)
+	}
+
+	for _, group := range roots {
+		sortNodes(group.Children)
+	}
+	sortNodes(roots)
+	return roots
+}
+
+func addProcess(total *models.ProcessTotals, p models.ProcessInfo) {
+	total.Processes++
+	total.Threads += max(p.Threads, 0)
+	total.CPUPercent += p.CPUPercent
+	total.MemoryRSS += p.MemoryRSS
+	total.MemoryPercent += p.MemoryPercent
+}
+
+func addTotals(total *models.ProcessTotals, other models.ProcessTotals) {
+	total.Processes += other.Processes
+	total.Threads += other.Threads
+	total.CPUPercent += other.CPUPercent
+	total.MemoryRSS += other.MemoryRSS
+	total.MemoryPercent += other.MemoryPercent
+}
+
+func sortNodes(nodes []*models.ProcessNode) {
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return nodes[i].Total.CPUPercent > nodes[j].Total.CPUPercent
+	})
+}
+
+// systemdUnit finds the deepest unit in cgroup path, e.g. nginx.service in /system.slice/nginx.service
+func systemdUnit(cgroup string) string {
+	parts := strings.Split(cgroup, "/")
+	for i := len(parts) - 1; i >= 0; i-- {
+		for _, suffix := range []string{".service", ".scope", ".socket", ".mount", ".swap"} {
+			if strings.HasSuffix(parts[i], suffix) {
+				return parts[i]
+			}
+		}
+	}
+	for i := len(parts) - 1; i >= 0; i-- { // processes outside of any unit still belong to a slice
+		if strings.HasSuffix(parts[i], ".slice") {
+			return parts[i]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,229 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	publicIPTimeout = 5 * time.Second
+	stunMagicCookie = 0x2112A442
+)
+
+// publicIPResolver looks the public address up in background on a long interval, collection only reads the cache
+type publicIPResolver struct {
+	providers []string // https://..., cmd:<command> or stun:<host:port>, tried in order
+	interval  time.Duration
+
+	mu          sync.Mutex
+	ip          string
+	err         string
+	lastAttempt time.Time
+	running     bool
+}
+
+// newPublicIPResolver returns nil when no providers are configured, lookup is disabled then
+func newPublicIPResolver(providers string, interval time.Duration) (*publicIPResolver, error) {
+	pr := &publicIPResolver{providers: splitPatterns(providers), interval: interval}
+	if len(pr.providers) == 0 {
+		return nil, nil
+	}
+	for _, provider := range pr.providers {
+		switch {
+		case strings.HasPrefix(provider, "https://"), strings.HasPrefix(provider, "http://"):
+		case strings.HasPrefix(provider, "cmd:"):
+			if strings.TrimSpace(strings.TrimPrefix(provider, "cmd:")) == "" {
+				return nil, fmt.Errorf("provider %q has no command", provider)
+			}
+		case strings.HasPrefix(provider, "stun:"):
+			if _, _, err := net.SplitHostPort(strings.TrimPrefix(provider, "stun:")); err != nil {
+				return nil, fmt.Errorf("stun provider %q must be stun:host:port", provider)
+			}
+		default:
+			return nil, fmt.Errorf("unknown provider %q, use an https url, cmd:<command> or stun:<host:port>", provider)
+		}
+	}
+	if interval < time.Minute {
+		return nil, fmt.Errorf("interval must be at least 60 seconds")
+	}
+	return pr, nil
+}
+
+// get returns cached address and error of the last lookup and starts a new lookup when it is due
+func (pr *publicIPResolver) get() (string, string) {
+	if pr == nil {
+		return "", ""
+	}
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+
+	if !pr.running && time.Since(pr.lastAttempt) >= pr.interval {
+		pr.running = true
+		pr.lastAttempt = time.Now()
+		go pr.refresh()
+	}
+	return pr.ip, pr.err
+}
+
+func (pr *publicIPResolver) refresh() {
+	var errs []string
+	ip := ""
+	for _, provider := range pr.providers {
+		found, err := lookupPublicIP(provider)
+		if err == nil {
+			ip = found
+			break
+		}
+		errs = append(errs, fmt.Sprintf("%s: %v", provider, err))
+	}
+
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+	pr.running = false
+	if ip == "" {
+		// the last known address stays, the error tells it could be stale
+		pr.err = "public IP lookup failed: " + strings.Join(errs, "; ")
+		return
+	}
+	if ip != pr.ip {
+		log.Printf("🌍 public IP is %s", ip)
+	}
+	pr.ip = ip
+	pr.err = ""
+}
+
+func lookupPublicIP(provider string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), publicIPTimeout)
+	defer cancel()
+
+	var output string
+	switch {
+	case strings.HasPrefix(provider, "cmd:"):
+		args := strings.Fields(strings.TrimPrefix(provider, "cmd:"))
+		data, err := exec.CommandContext(ctx, args[0], args[1:]...).Output()
+		if err != nil {
+			return "", err
+		}
+		output = string(data)
+	case strings.HasPrefix(provider, "stun:"):
+		return stunPublicIP(ctx, strings.TrimPrefix(provider, "stun:"))
+	default:
+		request, err := http.NewRequestWithContext(ctx, http.MethodGet, provider, nil)
+		if err != nil {
+			return "", err
+		}
+		response, err := http.DefaultClient.Do(request)
+		if err != nil {
+			return "", err
+		}
+		defer response.Body.Close()
+		if response.StatusCode != http.StatusOK {
+			return "", fmt.Errorf("status %d", response.StatusCode)
+		}
+		data, err := io.ReadAll(io.LimitReader(response.Body, 256))
+		if err != nil {
+			return "", err
+		}
+		output = string(data)
+	}
+
+	ip := net.ParseIP(strings.TrimSpace(output))
+	if ip == nil {
+		return "", fmt.Errorf("answer is not an IP address")
+	}
+	return ip.String(), nil
+}
+
+// stunPublicIP sends a STUN binding request (RFC 5389) and reads the mapped address from the response
+func stunPublicIP(ctx context.Context, server string) (string, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "udp", server)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	request := make([]byte, 20)
+	binary.BigEndian.PutUint16(request[0:], 0x0001) // binding request, no attributes
+	binary.BigEndian.PutUint32(request[4:], stunMagicCookie)
+	transaction := request[8:20]
+	if _, err := rand.Read(transaction); err != nil {
+		return "", err
+	}
+	if _, err := conn.Write(request); err != nil {
+		return "", err
+	}
+
+	response := make([]byte, 1500)
+	for {
+		n, err := conn.Read(response)
+		if err != nil {
+			return "", err
+		}
+		if n < 20 || binary.BigEndian.Uint16(response[0:]) != 0x0101 || !bytes.Equal(response[8:20], transaction) {
+			continue
+		}
+		return parseSTUNAddress(response[:n])
+	}
+}
+
+// parseSTUNAddress finds XOR-MAPPED-ADDRESS, or MAPPED-ADDRESS of old servers, in a binding response
+func parseSTUNAddress(message []byte) (string, error) {
+	length := min(int(binary.BigEndian.Uint16(message[2:]))+20, len(message))
+	var mapped net.IP
+	for offset := 20; offset+4 <= length; {
+		attrType := binary.BigEndian.Uint16(message[offset:])
+		attrLen := int(binary.BigEndian.Uint16(message[offset+2:]))
+		value := message[offset+4:]
+		if offset+4+attrLen > length {
+			break
+		}
+		value = value[:attrLen]
+		offset += 4 + (attrLen+3)/4*4 // values are padded to 4 bytes
+
+		if len(value) < 8 {
+			continue
+		}
+		ip := make(net.IP, 0, net.IPv6len)
+		switch value[1] {
+		case 1:
+			ip = append(ip, value[4:8]...)
+		case 2:
+			if len(value) < 20 {
+				continue
+			}
+			ip = append(ip, value[4:20]...)
+		default:
+			continue
+		}
+
+		switch attrType {
+		case 0x0020: // XOR-MAPPED-ADDRESS, xored with magic cookie and transaction id
+			for i := range ip {
+				ip[i] ^= message[4+i]
+			}
+			return ip.String(), nil
+		case 0x0001: // MAPPED-ADDRESS
+			mapped = ip
+		}
+	}
+	if mapped != nil {
+		return mapped.String(), nil
+	}
+	return "", errors.New("no mapped address in stun response")
+}
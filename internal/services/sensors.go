@@ -0,0 +1,297 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syspulse/internal/models"
+)
+
+// sensorCollector reads hwmon chips, thermal zones and power supplies from sysfs,
+// the files are cheap to read so there is no caching between collections
+type sensorCollector struct {
+	sysRoot string
+}
+
+func newSensorCollector(sysRoot string) *sensorCollector {
+	return &sensorCollector{sysRoot: sysRoot}
+}
+
+// collect returns nil when there are no sensors, e.g. in most virtual machines or without sysfs
+func (sc *sensorCollector) collect() *models.Sensors {
+	sensors := &models.Sensors{
+		Chips:         sc.readChips(),
+		ThermalZones:  sc.readThermalZones(),
+		PowerSupplies: sc.readPowerSupplies(),
+	}
+	if len(sensors.Chips) == 0 && len(sensors.ThermalZones) == 0 && len(sensors.PowerSupplies) == 0 {
+		return nil
+	}
+	return sensors
+}
+
+func (sc *sensorCollector) readChips() []models.SensorChip {
+	dirs, _ := filepath.Glob(filepath.Join(sc.sysRoot, "class", "hwmon", "hwmon*"))
+	sort.Slice(dirs, func(i, j int) bool { return sysfsIndex(dirs[i], "hwmon") < sysfsIndex(dirs[j], "hwmon") })
+
+	var chips []models.SensorChip
+	var devices []string // stable id of the device behind each chip
+	names := make(map[string]int)
+	for _, hwmonDir := range dirs {
+		// old drivers keep attributes in the device directory instead of the hwmon one
+		dir := hwmonDir
+		if _, err := os.Stat(filepath.Join(dir, "name")); err != nil {
+			dir = filepath.Join(dir, "device")
+		}
+		name := readSysfsString(filepath.Join(dir, "name"))
+		if name == "" {
+			continue
+		}
+
+		chip := models.SensorChip{ID: name, Name: name, Device: hwmonName(dir)}
+		chip.Temperatures = readTemperatures(dir)
+		chip.Fans = readFans(dir)
+		chip.Voltages = readVoltages(dir)
+		chip.Power = readPowerSensors(dir)
+		if len(chip.Temperatures)+len(chip.Fans)+len(chip.Voltages)+len(chip.Power) == 0 {
+			continue
+		}
+		names[name]++
+		chips = append(chips, chip)
+		devices = append(devices, chipDevice(hwmonDir))
+	}
+
+	// several nvme drives or dimms report the same name. hwmonN numbers follow driver load order
+	// and change between boots, the device (pci address, nvme controller, i2c address) does not
+	for i := range chips {
+		if names[chips[i].Name] > 1 {
+			chips[i].ID = chips[i].Name + "@" + devices[i]
+		}
+	}
+	return chips
+}
+
+// chipDevice returns the name the device link of a hwmon directory points to, e.g. 0000:01:00.0 or nvme1,
+// virtual chips without a device fall back to hwmonN
+func chipDevice(hwmonDir string) string {
+	target, err := os.Readlink(filepath.Join(hwmonDir, "device"))
+	if err != nil {
+		return filepath.Base(hwmonDir)
+	}
+	return filepath.Base(target)
+}
+
+func readTemperatures(dir string) []models.Temperature {
+	var temperatures []models.Temperature
+	for _, n := range sensorIndexes(dir, "temp") {
+		prefix := filepath.Join(dir, "temp"+n)
+		value, ok := readSysfsFloat(prefix + "_input")
+		if !ok {
+			continue // sensor is not connected or its driver failed to read it
+		}
+		temperature := models.Temperature{Label: sensorLabel(prefix, "temp"+n), Value: value / 1000}
+		if limit, ok := readSysfsFloat(prefix + "_max"); ok && limit > 0 {
+			limit /= 1000
+			temperature.Max = &limit
+		}
+		if crit, ok := readSysfsFloat(prefix + "_crit"); ok && crit > 0 {
+			crit /= 1000
+			temperature.Crit = &crit
+		}
+		temperatures = append(temperatures, temperature)
+	}
+	return temperatures
+}
+
+func readFans(dir string) []models.Fan {
+	var fans []models.Fan
+	for _, n := range sensorIndexes(dir, "fan") {
+		prefix := filepath.Join(dir, "fan"+n)
+		rpm, ok := readSysfsFloat(prefix + "_input")
+		if !ok {
+			continue
+		}
+		fan := models.Fan{Label: sensorLabel(prefix, "fan"+n), RPM: rpm}
+		if limit, ok := readSysfsFloat(prefix + "_min"); ok && limit > 0 {
+			fan.Min = &limit
+		}
+		fans = append(fans, fan)
+	}
+	return fans
+}
+
+func readVoltages(dir string) []models.Voltage {
+	var voltages []models.Voltage
+	for _, n := range sensorIndexes(dir, "in") {
+		prefix := filepath.Join(dir, "in"+n)
+		value, ok := readSysfsFloat(prefix + "_input")
+		if !ok {
+			continue
+		}
+		voltage := models.Voltage{Label: sensorLabel(prefix, "in"+n), Value: value / 1000}
+		if lower, ok := readSysfsFloat(prefix + "_min"); ok && lower > 0 {
+			lower /= 1000
+			voltage.Min = &lower
+		}
+		if upper, ok := readSysfsFloat(prefix + "_max"); ok && upper > 0 {
+			upper /= 1000
+			voltage.Max = &upper
+		}
+		voltages = append(voltages, voltage)
+	}
+	return voltages
+}
+
+func readPowerSensors(dir string) []models.PowerSensor {
+	var power []models.PowerSensor
+	for _, n := range sensorIndexes(dir, "power") {
+		prefix := filepath.Join(dir, "power"+n)
+		value, ok := readSysfsFloat(prefix + "_input")
+		if !ok {
+			if value, ok = readSysfsFloat(prefix + "_average"); !ok {
+				continue
+			}
+		}
+		power = append(power, models.PowerSensor{Label: sensorLabel(prefix, "power"+n), Value: value / 1000000})
+	}
+	return power
+}
+
+// sensorIndexes returns N of <kind>N_input and <kind>N_average files in numeric order
+func sensorIndexes(dir, kind string) []string {
+	paths, _ := filepath.Glob(filepath.Join(dir, kind+"[0-9]*_*"))
+	seen := make(map[string]bool)
+	var indexes []string
+	for _, path := range paths {
+		n, suffix, found := strings.Cut(strings.TrimPrefix(filepath.Base(path), kind), "_")
+		if !found || (suffix != "input" && suffix != "average") || seen[n] {
+			continue
+		}
+		if _, err := strconv.Atoi(n); err != nil {
+			continue
+		}
+		seen[n] = true
+		indexes = append(indexes, n)
+	}
+	sort.Slice(indexes, func(i, j int) bool {
+		a, _ := strconv.Atoi(indexes[i])
+		b, _ := strconv.Atoi(indexes[j])
+		return a < b
+	})
+	return indexes
+}
+
+func sensorLabel(prefix, fallback string) string {
+	if label := readSysfsString(prefix + "_label"); label != "" {
+		return label
+	}
+	return fallback
+}
+
+func (sc *sensorCollector) readThermalZones() []models.ThermalZone {
+	dirs, _ := filepath.Glob(filepath.Join(sc.sysRoot, "class", "thermal", "thermal_zone*"))
+	sort.Slice(dirs, func(i, j int) bool { return sysfsIndex(dirs[i], "thermal_zone") < sysfsIndex(dirs[j], "thermal_zone") })
+
+	var zones []models.ThermalZone
+	for _, dir := range dirs {
+		temp, ok := readSysfsFloat(filepath.Join(dir, "temp"))
+		if !ok {
+			continue // disabled zones fail with ENODATA
+		}
+		zone := models.ThermalZone{
+			Name:        filepath.Base(dir),
+			Type:        readSysfsString(filepath.Join(dir, "type")),
+			Temperature: temp / 1000,
+		}
+		trips, _ := filepath.Glob(filepath.Join(dir, "trip_point_*_type"))
+		for _, trip := range trips {
+			if readSysfsString(trip) != "critical" {
+				continue
+			}
+			if crit, ok := readSysfsFloat(strings.TrimSuffix(trip, "_type") + "_temp"); ok && crit > 0 {
+				crit /= 1000
+				zone.Crit = &crit
+				break
+			}
+		}
+		zones = append(zones, zone)
+	}
+	return zones
+}
+
+func (sc *sensorCollector) readPowerSupplies() []models.PowerSupply {
+	dirs, _ := filepath.Glob(filepath.Join(sc.sysRoot, "class", "power_supply", "*"))
+	sort.Strings(dirs)
+
+	var supplies []models.PowerSupply
+	for _, dir := range dirs {
+		supplyType := readSysfsString(filepath.Join(dir, "type"))
+		if supplyType == "" {
+			continue
+		}
+		// batteries of mice and keyboards report scope Device, only the system's own supplies matter
+		if readSysfsString(filepath.Join(dir, "scope")) == "Device" {
+			continue
+		}
+		supply := models.PowerSupply{
+			Name:   filepath.Base(dir),
+			Type:   supplyType,
+			Status: readSysfsString(filepath.Join(dir, "status")),
+		}
+		if value, ok := readSysfsFloat(filepath.Join(dir, "online")); ok {
+			online := value != 0
+			supply.Online = &online
+		}
+		if capacity, ok := readSysfsFloat(filepath.Join(dir, "capacity")); ok {
+			supply.Capacity = &capacity
+		}
+
+		// values are in micro units, some batteries report only current and power is computed from it
+		microVolts, hasVoltage := readSysfsFloat(filepath.Join(dir, "voltage_now"))
+		if hasVoltage {
+			volts := microVolts / 1000000
+			supply.Voltage = &volts
+		}
+		if microWatts, ok := readSysfsFloat(filepath.Join(dir, "power_now")); ok {
+			watts := microWatts / 1000000
+			supply.Power = &watts
+		} else if microAmps, ok := readSysfsFloat(filepath.Join(dir, "current_now")); ok && hasVoltage {
+			watts := microAmps / 1000000 * microVolts / 1000000
+			supply.Power = &watts
+		}
+		supplies = append(supplies, supply)
+	}
+	return supplies
+}
+
+func readSysfsString(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+func readSysfsFloat(path string) (float64, bool) {
+	value, err := strconv.ParseFloat(readSysfsString(path), 64)
+	return value, err == nil
+}
+
+// sysfsIndex returns N of <prefix>N, so hwmon10 sorts after hwmon9
+func sysfsIndex(path, prefix string) int {
+	n, err := strconv.Atoi(strings.TrimPrefix(filepath.Base(path), prefix))
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+// hwmonName returns hwmonN of a chip directory, which is the device subdirectory for old drivers
+func hwmonName(path string) string {
+	if filepath.Base(path) == "device" {
+		path = filepath.Dir(path)
+	}
+	return filepath.Base(path)
+}
@@ -0,0 +1,213 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"syspulse/internal/models"
+	"testing"
+	"time"
+)
+
+// fakeSysfs builds a sysfs tree in a temp dir, files are path -> content and links are path -> target
+func fakeSysfs(t *testing.T, files, links map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name, target := range links {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Symlink(target, path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestSensorCollector(t *testing.T) {
+	sysRoot := fakeSysfs(t, map[string]string{
+		"class/hwmon/hwmon0/name":        "coretemp",
+		"class/hwmon/hwmon0/temp1_input": "45000",
+		"class/hwmon/hwmon0/temp1_label": "Package id 0",
+		"class/hwmon/hwmon0/temp1_max":   "80000",
+		"class/hwmon/hwmon0/temp1_crit":  "100000",
+		"class/hwmon/hwmon0/temp2_label": "Core 1", // no _input, the sensor is not connected
+		"class/hwmon/hwmon0/temp2_crit":  "100000",
+
+		// old driver: attributes live in the device directory
+		"devices/platform/it87.656/name":       "it87",
+		"devices/platform/it87.656/fan1_input": "1200",
+		"devices/platform/it87.656/fan1_min":   "600",
+		"devices/platform/it87.656/in0_input":  "1104",
+		"devices/platform/it87.656/in0_label":  "Vcore",
+
+		// two drives with the same driver name
+		"class/hwmon/hwmon2/name":            "nvme",
+		"class/hwmon/hwmon2/temp1_input":     "38850",
+		"class/hwmon/hwmon10/name":           "nvme",
+		"class/hwmon/hwmon10/temp1_input":    "41850",
+		"class/hwmon/hwmon10/power1_average": "15000000",
+
+		"class/thermal/thermal_zone0/type":              "x86_pkg_temp",
+		"class/thermal/thermal_zone0/temp":              "50000",
+		"class/thermal/thermal_zone0/trip_point_0_type": "passive",
+		"class/thermal/thermal_zone0/trip_point_0_temp": "90000",
+		"class/thermal/thermal_zone0/trip_point_1_type": "critical",
+		"class/thermal/thermal_zone0/trip_point_1_temp": "105000",
+		"class/thermal/thermal_zone1/type":              "disabled", // no temp, disabled zones fail to read it
+
+		"class/power_supply/BAT0/type":        "Battery",
+		"class/power_supply/BAT0/status":      "Discharging",
+		"class/power_supply/BAT0/capacity":    "80",
+		"class/power_supply/BAT0/voltage_now": "12000000",
+		"class/power_supply/BAT0/current_now": "1500000", // no power_now, power is current × voltage
+		"class/power_supply/AC/type":          "Mains",
+		"class/power_supply/AC/online":        "1",
+		"class/power_supply/hid-mouse/type":   "Battery",
+		"class/power_supply/hid-mouse/scope":  "Device",
+	}, map[string]string{
+		"class/hwmon/hwmon0/device":  "../../../devices/platform/coretemp.0",
+		"class/hwmon/hwmon1/device":  "../../../devices/platform/it87.656",
+		"class/hwmon/hwmon2/device":  "../../nvme/nvme1",
+		"class/hwmon/hwmon10/device": "../../nvme/nvme0",
+	})
+
+	sensors := newSensorCollector(sysRoot).collect()
+	if sensors == nil {
+		t.Fatal("no sensors")
+	}
+
+	if len(sensors.Chips) != 4 {
+		t.Fatalf("chips = %+v, want 4", sensors.Chips)
+	}
+	coretemp, it87, nvme1, nvme0 := sensors.Chips[0], sensors.Chips[1], sensors.Chips[2], sensors.Chips[3]
+
+	if coretemp.ID != "coretemp" || coretemp.Device != "hwmon0" || len(coretemp.Temperatures) != 1 {
+		t.Fatalf("coretemp = %+v", coretemp)
+	}
+	temperature := coretemp.Temperatures[0]
+	if temperature.Label != "Package id 0" || temperature.Value != 45 || *temperature.Max != 80 || *temperature.Crit != 100 {
+		t.Errorf("coretemp temperature = %+v", temperature)
+	}
+
+	if it87.ID != "it87" || it87.Device != "hwmon1" || len(it87.Fans) != 1 || len(it87.Voltages) != 1 {
+		t.Fatalf("legacy chip = %+v", it87)
+	}
+	if fan := it87.Fans[0]; fan.Label != "fan1" || fan.RPM != 1200 || *fan.Min != 600 {
+		t.Errorf("legacy fan = %+v", fan)
+	}
+	if voltage := it87.Voltages[0]; voltage.Label != "Vcore" || voltage.Value != 1.104 {
+		t.Errorf("legacy voltage = %+v", voltage)
+	}
+
+	// hwmon10 sorts after hwmon2, ids come from the drives, not from the hwmon numbers
+	if nvme1.ID != "nvme@nvme1" || nvme1.Device != "hwmon2" || nvme1.Temperatures[0].Value != 38.85 {
+		t.Errorf("first nvme = %+v", nvme1)
+	}
+	if nvme0.ID != "nvme@nvme0" || nvme0.Device != "hwmon10" || len(nvme0.Power) != 1 || nvme0.Power[0].Value != 15 {
+		t.Errorf("second nvme = %+v", nvme0)
+	}
+
+	if len(sensors.ThermalZones) != 1 {
+		t.Fatalf("thermal zones = %+v, want 1", sensors.ThermalZones)
+	}
+	if zone := sensors.ThermalZones[0]; zone.Name != "thermal_zone0" || zone.Type != "x86_pkg_temp" || zone.Temperature != 50 || zone.Crit == nil || *zone.Crit != 105 {
+		t.Errorf("thermal zone = %+v", zone)
+	}
+
+	if len(sensors.PowerSupplies) != 2 {
+		t.Fatalf("power supplies = %+v, want AC and BAT0", sensors.PowerSupplies)
+	}
+	ac, battery := sensors.PowerSupplies[0], sensors.PowerSupplies[1]
+	if ac.Name != "AC" || ac.Online == nil || !*ac.Online {
+		t.Errorf("ac = %+v", ac)
+	}
+	if battery.Name != "BAT0" || *battery.Capacity != 80 || *battery.Voltage != 12 || battery.Power == nil || *battery.Power != 18 {
+		t.Errorf("battery = %+v", battery)
+	}
+}
+
+func TestSensorCollectorWithoutSensors(t *testing.T) {
+	if sensors := newSensorCollector(t.TempDir()).collect(); sensors != nil {
+		t.Errorf("sensors = %+v, want nil", sensors)
+	}
+}
+
+func TestCheckSensors(t *testing.T) {
+	as := NewAlertService()
+	now := time.Now()
+	crit := 100.0
+	chip := func(value float64) *models.Sensors {
+		return &models.Sensors{Chips: []models.SensorChip{{
+			ID:           "nvme@nvme0",
+			Temperatures: []models.Temperature{{Label: "Composite", Value: value, Crit: &crit}},
+		}}}
+	}
+	const alertType = "SENSOR:nvme@nvme0/Composite"
+
+	levels := func(alerts []models.Alert) []string {
+		var result []string
+		for _, alert := range alerts {
+			if alert.Type != alertType {
+				t.Errorf("alert type = %s, want %s", alert.Type, alertType)
+			}
+			result = append(result, alert.Level)
+		}
+		return result
+	}
+	expect := func(step string, alerts []models.Alert, want ...string) {
+		t.Helper()
+		got := levels(alerts)
+		if len(got) != len(want) || (len(want) == 1 && got[0] != want[0]) {
+			t.Errorf("%s: alerts %v, want %v", step, got, want)
+		}
+	}
+
+	expect("cool", as.checkSensors(chip(60), now))
+	expect("near critical", as.checkSensors(chip(96), now), "warning")
+	expect("still near critical", as.checkSensors(chip(97), now))
+	expect("critical", as.checkSensors(chip(101), now), "critical")
+	expect("still critical", as.checkSensors(chip(102), now))
+	// hovering around the critical point does not flap between levels
+	expect("back to warning", as.checkSensors(chip(98), now))
+	expect("critical again", as.checkSensors(chip(100), now))
+
+	expect("cooled down", as.checkSensors(chip(80), now))
+	if as.activeAlerts[alertType] || as.sensorLevels[alertType] != "" {
+		t.Error("alert is still active after the sensor cooled down")
+	}
+
+	expect("critical after cooling", as.checkSensors(chip(105), now), "critical")
+	expect("drive removed", as.checkSensors(&models.Sensors{}, now))
+	if as.activeAlerts[alertType] || len(as.sensorLevels) != 0 {
+		t.Error("alert of a removed sensor is still active")
+	}
+	expect("no sensors at all", as.checkSensors(nil, now))
+}
+
+func TestCheckSensorsWhileClearingHistory(t *testing.T) {
+	as := NewAlertService()
+	crit := 100.0
+	sensors := &models.Sensors{ThermalZones: []models.ThermalZone{{Name: "thermal_zone0", Temperature: 101, Crit: &crit}}}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range 100 {
+			as.ClearHistory()
+		}
+	}()
+	for range 100 {
+		as.checkSensors(sensors, time.Now())
+	}
+	<-done
+}
@@ -0,0 +1,387 @@
+package services
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syspulse/internal/models"
+	"time"
+)
+
+const (
+	DefaultConnectionLimit = 500
+	MaxConnectionLimit     = 10000
+
+	// finding socket owners reads every fd of every process, it is not repeated more often than this
+	socketOwnerRefresh = 10 * time.Second
+)
+
+var socketTables = []string{"tcp", "tcp6", "udp", "udp6"}
+
+// st column of /proc/net/tcp, names are the same gopsutil uses
+var tcpStates = map[string]string{
+	"01": "ESTABLISHED",
+	"02": "SYN_SENT",
+	"03": "SYN_RECV",
+	"04": "FIN_WAIT1",
+	"05": "FIN_WAIT2",
+	"06": "TIME_WAIT",
+	"07": "CLOSE",
+	"08": "CLOSE_WAIT",
+	"09": "LAST_ACK",
+	"0A": "LISTEN",
+	"0B": "CLOSING",
+	"0C": "NEW_SYN_RECV",
+}
+
+// socketEntry is one line of /proc/net/{tcp,tcp6,udp,udp6}
+type socketEntry struct {
+	protocol   string
+	localAddr  string
+	localPort  uint32
+	remoteAddr string
+	remotePort uint32
+	state      string
+	txQueue    uint64
+	rxQueue    uint64
+	uid        uint32
+	inode      uint64
+}
+
+func (se socketEntry) listening() bool {
+	if strings.HasPrefix(se.protocol, "udp") {
+		return se.remotePort == 0 && se.localPort != 0
+	}
+	return se.state == "LISTEN"
+}
+
+// socketCollector summarizes socket tables and /proc/net/snmp counters
+type socketCollector struct {
+	procRoot string
+	prevSNMP map[string]uint64
+	prevTime time.Time
+
+	mu         sync.Mutex       // guards owners, connection queries run outside collection
+	owners     map[uint64]int32 // socket inode -> pid, replaced as a whole on rescan
+	ownersTime time.Time
+}
+
+func newSocketCollector(procRoot string) *socketCollector {
+	return &socketCollector{procRoot: procRoot, owners: make(map[uint64]int32)}
+}
+
+// collect returns nil on systems without /proc/net
+func (sc *socketCollector) collect(processes []models.ProcessInfo) *models.SocketStats {
+	entries, err := readSockets(sc.procRoot)
+	if err != nil {
+		return nil
+	}
+
+	stats := &models.SocketStats{TCPStates: make(map[string]int), Listening: []models.ListeningSocket{}}
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.protocol, "tcp") {
+			stats.TCP++
+			stats.TCPStates[entry.state]++
+		} else {
+			stats.UDP++
+		}
+	}
+	owners := sc.ownersOf(entries, socketEntry.listening)
+
+	names := processNames(processes)
+	for _, entry := range entries {
+		if !entry.listening() {
+			continue
+		}
+		listening := models.ListeningSocket{Protocol: entry.protocol, Address: entry.localAddr, Port: entry.localPort}
+		if pid, ok := owners[entry.inode]; ok {
+			listening.PID = pid
+			listening.Process = names[pid]
+		}
+		stats.Listening = append(stats.Listening, listening)
+	}
+	sort.Slice(stats.Listening, func(i, j int) bool {
+		a, b := stats.Listening[i], stats.Listening[j]
+		if a.Port != b.Port {
+			return a.Port < b.Port
+		}
+		return a.Protocol < b.Protocol
+	})
+
+	sc.updateRates(stats, time.Now())
+	return stats
+}
+
+// ownersOf returns cached socket owners. When an entry picked by needsOwner has no owner,
+// the fd links are scanned again, but not more often than socketOwnerRefresh
+func (sc *socketCollector) ownersOf(entries []socketEntry, needsOwner func(socketEntry) bool) map[uint64]int32 {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	if now := time.Now(); now.Sub(sc.ownersTime) >= socketOwnerRefresh {
+		for _, entry := range entries {
+			if _, ok := sc.owners[entry.inode]; !ok && entry.inode != 0 && needsOwner(entry) {
+				sc.owners = socketOwners(sc.procRoot)
+				sc.ownersTime = now
+				break
+			}
+		}
+	}
+	return sc.owners
+}
+
+// updateRates fills tcp and udp error rates from /proc/net/snmp
+func (sc *socketCollector) updateRates(stats *models.SocketStats, now time.Time) {
+	current, err := readSNMP(filepath.Join(sc.procRoot, "net", "snmp"))
+	if err != nil {
+		return
+	}
+	if sc.prevSNMP != nil {
+		if elapsed := now.Sub(sc.prevTime).Seconds(); elapsed > 0 {
+			delta := func(key string) float64 {
+				return float64(counterDelta(sc.prevSNMP[key], current[key]))
+			}
+			stats.RetransmitRate = delta("Tcp.RetransSegs") / elapsed
+			if sent := delta("Tcp.OutSegs"); sent > 0 {
+				stats.RetransmitPercent = delta("Tcp.RetransSegs") / sent * 100
+			}
+			stats.ResetRate = delta("Tcp.OutRsts") / elapsed
+			stats.EstabResetRate = delta("Tcp.EstabResets") / elapsed
+			stats.AttemptFailRate = delta("Tcp.AttemptFails") / elapsed
+			stats.UDPInErrorRate = delta("Udp.InErrors") / elapsed
+			stats.UDPRcvbufErrorRate = delta("Udp.RcvbufErrors") / elapsed
+			stats.UDPNoPortRate = delta("Udp.NoPorts") / elapsed
+		}
+	}
+	sc.prevSNMP = current
+	sc.prevTime = now
+}
+
+type ConnectionQuery struct {
+	Port     uint32 // local or remote port, 0 for any
+	PID      int32  // owning process, 0 for any
+	Process  string // exact name of owning process
+	State    string // tcp state, e.g. ESTABLISHED
+	Protocol string // tcp or udp match both families, tcp6 or udp6 only one
+	Limit    int
+}
+
+// QueryConnections reads socket tables now, owners come from the cache shared with collection
+func (ms *MetricsService) QueryConnections(query ConnectionQuery) (models.ConnectionList, error) {
+	if query.Limit <= 0 {
+		query.Limit = DefaultConnectionLimit
+	}
+	if query.Limit > MaxConnectionLimit {
+		query.Limit = MaxConnectionLimit
+	}
+
+	procRoot := ms.sockets.procRoot
+	entries, err := readSockets(procRoot)
+	if err != nil {
+		return models.ConnectionList{}, fmt.Errorf("failed to read socket tables: %w", err)
+	}
+	owners := ms.sockets.ownersOf(entries, func(socketEntry) bool { return true })
+	names := processNames(ms.GetLatestMetrics().Processes)
+
+	list := models.ConnectionList{Connections: []models.Connection{}, Timestamp: time.Now()}
+	for _, entry := range entries {
+		pid := owners[entry.inode]
+		if query.Port != 0 && entry.localPort != query.Port && entry.remotePort != query.Port {
+			continue
+		}
+		if query.PID != 0 && pid != query.PID {
+			continue
+		}
+		if query.Process != "" && names[pid] != query.Process {
+			continue
+		}
+		if query.State != "" && !strings.EqualFold(entry.state, query.State) {
+			continue
+		}
+		if query.Protocol != "" && entry.protocol != query.Protocol && strings.TrimSuffix(entry.protocol, "6") != query.Protocol {
+			continue
+		}
+
+		list.Total++
+		if len(list.Connections) >= query.Limit {
+			continue
+		}
+		list.Connections = append(list.Connections, models.Connection{
+			Protocol:   entry.protocol,
+			LocalAddr:  entry.localAddr,
+			LocalPort:  entry.localPort,
+			RemoteAddr: entry.remoteAddr,
+			RemotePort: entry.remotePort,
+			State:      entry.state,
+			UID:        entry.uid,
+			TxQueue:    entry.txQueue,
+			RxQueue:    entry.rxQueue,
+			PID:        pid,
+			Process:    names[pid],
+		})
+	}
+	return list, nil
+}
+
+// readSockets reads all socket tables, a missing table (e.g. no ipv6) is skipped
+func readSockets(procRoot string) ([]socketEntry, error) {
+	var entries []socketEntry
+	var lastErr error
+	read := 0
+	for _, protocol := range socketTables {
+		data, err := os.ReadFile(filepath.Join(procRoot, "net", protocol))
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		read++
+		entries = append(entries, parseSocketTable(string(data), protocol)...)
+	}
+	if read == 0 {
+		return nil, lastErr
+	}
+	return entries, nil
+}
+
+// parseSocketTable parses lines like
+// "0: 0100007F:0277 00000000:0000 0A 00000000:00000000 00:00000000 00000000 0 0 12345 1 ..."
+func parseSocketTable(data, protocol string) []socketEntry {
+	lines := strings.Split(data, "\n")
+	entries := make([]socketEntry, 0, len(lines))
+	for _, line := range lines[1:] {
+		fields := strings.Fields(line)
+		if len(fields) < 10 {
+			continue
+		}
+		entry := socketEntry{protocol: protocol}
+		var ok bool
+		if entry.localAddr, entry.localPort, ok = parseSocketAddr(fields[1]); !ok {
+			continue
+		}
+		if entry.remoteAddr, entry.remotePort, ok = parseSocketAddr(fields[2]); !ok {
+			continue
+		}
+		if strings.HasPrefix(protocol, "tcp") {
+			entry.state = tcpStates[fields[3]]
+			if entry.state == "" {
+				entry.state = "UNKNOWN"
+			}
+		} else {
+			entry.state = "NONE"
+		}
+		if tx, rx, found := strings.Cut(fields[4], ":"); found {
+			entry.txQueue, _ = strconv.ParseUint(tx, 16, 64)
+			entry.rxQueue, _ = strconv.ParseUint(rx, 16, 64)
+		}
+		uid, _ := strconv.ParseUint(fields[7], 10, 32)
+		entry.uid = uint32(uid)
+		entry.inode, _ = strconv.ParseUint(fields[9], 10, 64)
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// parseSocketAddr decodes "0100007F:0035", address is hex of 32 bit words in host (little endian) order
+func parseSocketAddr(value string) (string, uint32, bool) {
+	addrHex, portHex, found := strings.Cut(value, ":")
+	if !found {
+		return "", 0, false
+	}
+	port, err := strconv.ParseUint(portHex, 16, 16)
+	if err != nil {
+		return "", 0, false
+	}
+	raw, err := hex.DecodeString(addrHex)
+	if err != nil || (len(raw) != net.IPv4len && len(raw) != net.IPv6len) {
+		return "", 0, false
+	}
+	ip := make(net.IP, len(raw))
+	for word := 0; word < len(raw); word += 4 {
+		for i := 0; i < 4; i++ {
+			ip[word+i] = raw[word+3-i]
+		}
+	}
+	return ip.String(), uint32(port), true
+}
+
+// socketOwners maps socket inodes to pids by reading fd links, processes we can't read are skipped
+func socketOwners(procRoot string) map[uint64]int32 {
+	owners := make(map[uint64]int32)
+	dirs, err := os.ReadDir(procRoot)
+	if err != nil {
+		return owners
+	}
+	for _, dir := range dirs {
+		pid, err := strconv.ParseInt(dir.Name(), 10, 32)
+		if err != nil {
+			continue
+		}
+		inodes, err := socketInodes(filepath.Join(procRoot, dir.Name(), "fd"))
+		if err != nil {
+			continue
+		}
+		for inode := range inodes {
+			owners[inode] = int32(pid)
+		}
+	}
+	return owners
+}
+
+// socketInodes returns inodes of sockets behind "socket:[12345]" fd links
+func socketInodes(fdDir string) (map[uint64]bool, error) {
+	fds, err := os.ReadDir(fdDir)
+	if err != nil {
+		return nil, err
+	}
+	inodes := make(map[uint64]bool)
+	for _, fd := range fds {
+		link, err := os.Readlink(filepath.Join(fdDir, fd.Name()))
+		if err != nil || !strings.HasPrefix(link, "socket:[") {
+			continue
+		}
+		inode, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(link, "socket:["), "]"), 10, 64)
+		if err == nil {
+			inodes[inode] = true
+		}
+	}
+	return inodes, nil
+}
+
+// readSNMP parses header and value line pairs of /proc/net/snmp into "Tcp.RetransSegs" style keys,
+// negative values (Tcp MaxConn is -1) are skipped
+func readSNMP(path string) (map[string]uint64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]uint64)
+	lines := strings.Split(string(data), "\n")
+	for i := 0; i+1 < len(lines); i += 2 {
+		names := strings.Fields(lines[i])
+		numbers := strings.Fields(lines[i+1])
+		if len(names) == 0 || len(names) != len(numbers) || names[0] != numbers[0] {
+			continue
+		}
+		prefix := strings.TrimSuffix(names[0], ":")
+		for j := 1; j < len(names); j++ {
+			if value, err := strconv.ParseUint(numbers[j], 10, 64); err == nil {
+				values[prefix+"."+names[j]] = value
+			}
+		}
+	}
+	return values, nil
+}
+
+func processNames(processes []models.ProcessInfo) map[int32]string {
+	names := make(map[int32]string, len(processes))
+	for _, p := range processes {
+		names[p.PID] = p.Process
+	}
+	return names
+}
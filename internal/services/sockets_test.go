@@ -0,0 +1,47 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func addSocketFD(t *testing.T, procRoot, pid, fd, inode string) {
+	t.Helper()
+	dir := filepath.Join(procRoot, pid, "fd")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("socket:["+inode+"]", filepath.Join(dir, fd)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSocketOwnersRescanIsThrottled(t *testing.T) {
+	procRoot := t.TempDir()
+	addSocketFD(t, procRoot, "100", "3", "1000")
+	sc := newSocketCollector(procRoot)
+	everySocket := func(socketEntry) bool { return true }
+
+	owners := sc.ownersOf([]socketEntry{{inode: 1000}}, everySocket)
+	if owners[1000] != 100 {
+		t.Fatalf("owner of 1000 = %d, want 100", owners[1000])
+	}
+
+	// a new socket within the refresh interval is not looked up
+	addSocketFD(t, procRoot, "200", "4", "2000")
+	if owners = sc.ownersOf([]socketEntry{{inode: 1000}, {inode: 2000}}, everySocket); owners[2000] != 0 {
+		t.Errorf("fd links were scanned again within %v", socketOwnerRefresh)
+	}
+
+	// sockets without inode (time-wait) never trigger a scan
+	sc.ownersTime = time.Now().Add(-socketOwnerRefresh)
+	if owners = sc.ownersOf([]socketEntry{{inode: 1000}, {inode: 0}}, everySocket); owners[2000] != 0 {
+		t.Error("socket without inode triggered a scan")
+	}
+
+	if owners = sc.ownersOf([]socketEntry{{inode: 2000}}, everySocket); owners[2000] != 200 {
+		t.Errorf("owner of 2000 after refresh = %d, want 200", owners[2000])
+	}
+}
@@ -0,0 +1,141 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syspulse/internal/models"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/host"
+)
+
+// host facts rarely change, they are read again only after this interval
+const systemInfoRefresh = time.Minute
+
+// systemInfoCollector caches host facts, uptime is computed from boot time on every collection
+type systemInfoCollector struct {
+	procRoot  string
+	cpuModel  string
+	info      models.SystemInfo
+	refreshed time.Time
+}
+
+func newSystemInfoCollector(procRoot, cpuModel string) *systemInfoCollector {
+	return &systemInfoCollector{procRoot: procRoot, cpuModel: cpuModel}
+}
+
+func (sc *systemInfoCollector) collect() models.SystemInfo {
+	now := time.Now()
+	if now.Sub(sc.refreshed) >= systemInfoRefresh {
+		sc.refresh()
+		sc.refreshed = now
+	}
+
+	info := sc.info
+	if !info.BootTime.IsZero() {
+		info.Uptime = uint64(now.Sub(info.BootTime).Seconds())
+	}
+	return info
+}
+
+func (sc *systemInfoCollector) refresh() {
+	hostname, _ := os.Hostname()
+	info := models.SystemInfo{
+		Hostname: hostname,
+		OS:       runtime.GOOS,
+		Platform: runtime.GOARCH,
+		CPUModel: sc.cpuModel,
+	}
+
+	// /proc/stat of the host when procfs is mounted from it, gopsutil elsewhere
+	if btime, err := readBootTime(sc.procRoot); err == nil {
+		info.BootTime = time.Unix(btime, 0)
+	} else if btime, err := host.BootTime(); err == nil {
+		info.BootTime = time.Unix(int64(btime), 0)
+	}
+
+	if stat, err := host.Info(); err == nil {
+		info.Kernel = stat.KernelVersion
+		info.Distribution = stat.Platform
+		info.DistributionVersion = stat.PlatformVersion
+		info.Virtualization = stat.VirtualizationSystem
+		info.VirtualizationRole = stat.VirtualizationRole
+		info.MachineID = stat.HostID
+	}
+	if id := readMachineID(); id != "" {
+		info.MachineID = id
+	}
+	info.Container = detectContainer(sc.procRoot)
+
+	info.Timezone, info.UTCOffset = time.Now().Zone()
+	if name := timezoneName(); name != "" {
+		info.Timezone = name
+	}
+
+	if users, err := host.Users(); err == nil {
+		info.Users = len(users)
+	}
+	sc.info = info
+}
+
+func readMachineID() string {
+	for _, path := range []string{"/etc/machine-id", "/var/lib/dbus/machine-id"} {
+		if data, err := os.ReadFile(path); err == nil {
+			if id := strings.TrimSpace(string(data)); id != "" {
+				return id
+			}
+		}
+	}
+	return ""
+}
+
+// detectContainer checks marker files and env of container runtimes, then cgroup of pid 1
+func detectContainer(procRoot string) string {
+	if os.Getenv("KUBERNETES_SERVICE_HOST") != "" {
+		return "kubernetes"
+	}
+	if _, err := os.Stat("/run/.containerenv"); err == nil {
+		return "podman"
+	}
+	if _, err := os.Stat("/.dockerenv"); err == nil {
+		return "docker"
+	}
+	if container := os.Getenv("container"); container != "" {
+		return container // set by systemd-nspawn, lxc and podman
+	}
+
+	data, err := os.ReadFile(filepath.Join(procRoot, "1", "cgroup"))
+	if err != nil {
+		return ""
+	}
+	cgroup := string(data)
+	switch {
+	case strings.Contains(cgroup, "kubepods"):
+		return "kubernetes"
+	case strings.Contains(cgroup, "docker"):
+		return "docker"
+	case strings.Contains(cgroup, "lxc"):
+		return "lxc"
+	}
+	return ""
+}
+
+// timezoneName returns IANA name from TZ or the /etc/localtime link, empty when unknown
+func timezoneName() string {
+	if tz := strings.TrimPrefix(os.Getenv("TZ"), ":"); tz != "" && !strings.HasPrefix(tz, "/") {
+		return tz
+	}
+	if data, err := os.ReadFile("/etc/timezone"); err == nil {
+		if name := strings.TrimSpace(string(data)); name != "" {
+			return name
+		}
+	}
+	if target, err := os.Readlink("/etc/localtime"); err == nil {
+		if _, name, found := strings.Cut(target, "zoneinfo/"); found {
+			return name
+		}
+	}
+	return ""
+}
@@ -84,6 +84,29 @@ class SysPulseMonitor {
                 }
             });
         });
+
+        const coresCtx = document.getElementById('cpu-cores-chart');
+        if (coresCtx) {
+            this.charts.cores = new Chart(coresCtx, {
+                type: 'bar',
+                data: {
+                    labels: [],
+                    datasets: [{
+                        data: [],
+                        backgroundColor: this.getChartColor('cpu')
+                    }]
+                },
+                options: {
+                    responsive: true,
+                    maintainAspectRatio: false,
+                    plugins: { legend: { display: false } },
+                    scales: {
+                        y: { min: 0, max: 100 }
+                    },
+                    animation: { duration: 0 }
+                }
+            });
+        }
     }
 
     getChartColor(type) {
@@ -93,7 +116,10 @@ class SysPulseMonitor {
 
     updateChartColors() {
         Object.keys(this.charts).forEach(type => {
-            if (this.charts[type]) {
+            if (type === 'cores') {
+                this.charts.cores.data.datasets[0].backgroundColor = this.getChartColor('cpu');
+                this.charts.cores.update('none');
+            } else if (this.charts[type]) {
                 this.charts[type].data.datasets[0].borderColor = this.getChartColor(type);
                 this.charts[type].update('none');
             }
@@ -140,25 +166,107 @@ class SysPulseMonitor {
         if (data.cpu) {
             this.updateElement('cpu-usage', `${data.cpu.usage.toFixed(1)}%`);
             this.updateElement('cpu-details', `${data.cpu.cores} ядер | Load: ${data.cpu.load1.toFixed(2)}`);
+            this.updateCPUCores(data.cpu);
         }
         
         if (data.memory) {
-            this.updateElement('memory-usage', `${data.memory.usage.toFixed(1)}%`);
-            if (data.memory.used && data.memory.total) {
-                const usedGB = (data.memory.used / 1024 / 1024 / 1024).toFixed(1);
-                const totalGB = (data.memory.total / 1024 / 1024 / 1024).toFixed(1);
-                this.updateElement('memory-details', `${usedGB}GB / ${totalGB}GB`);
+            if (data.memory.error) {
+                this.updateElement('memory-usage', '-');
+                this.updateElement('memory-details', data.memory.error);
+            } else {
+                this.updateElement('memory-usage', `${data.memory.usage.toFixed(1)}%`);
+                if (data.memory.used && data.memory.total) {
+                    const usedGB = (data.memory.used / 1024 / 1024 / 1024).toFixed(1);
+                    const totalGB = (data.memory.total / 1024 / 1024 / 1024).toFixed(1);
+                    const swap = data.memory.swap && data.memory.swap.total
+                        ? ` | Swap: ${data.memory.swap.usage.toFixed(0)}%` : '';
+                    this.updateElement('memory-details', `${usedGB}GB / ${totalGB}GB${swap}`);
+                }
             }
         }
         
         if (data.disk) {
-            this.updateElement('disk-usage', `${data.disk.usage.toFixed(1)}%`);
-            if (data.disk.used && data.disk.total) {
-                const usedGB = (data.disk.used / 1024 / 1024 / 1024).toFixed(1);
-                const totalGB = (data.disk.total / 1024 / 1024 / 1024).toFixed(1);
-                this.updateElement('disk-details', `${usedGB}GB / ${totalGB}GB`);
+            if (data.disk.error) {
+                this.updateElement('disk-usage', '-');
+                this.updateElement('disk-details', data.disk.error);
+            } else {
+                this.updateElement('disk-usage', `${data.disk.usage.toFixed(1)}%`);
+                if (data.disk.used && data.disk.total) {
+                    const usedGB = (data.disk.used / 1024 / 1024 / 1024).toFixed(1);
+                    const totalGB = (data.disk.total / 1024 / 1024 / 1024).toFixed(1);
+                    this.updateElement('disk-details', `${data.disk.mountpoint || ''} ${usedGB}GB / ${totalGB}GB`);
+                }
             }
         }
+
+        this.updateFilesystems(data.filesystems);
+    }
+
+    updateFilesystems(filesystems) {
+        const container = document.getElementById('filesystems');
+        if (!container || !Array.isArray(filesystems)) return;
+
+        if (filesystems.length === 0) {
+            container.innerHTML = '<div class="no-processes">Нет данных</div>';
+            return;
+        }
+
+        const rows = filesystems.map(fs => {
+            const usage = fs.error
+                ? `<td colspan="3">${fs.error}</td>`
+                : `<td>${(fs.used / 1024 / 1024 / 1024).toFixed(1)}GB / ${(fs.total / 1024 / 1024 / 1024).toFixed(1)}GB</td>
+                   <td>${fs.usage.toFixed(1)}%</td>
+                   <td>${fs.inodes_total ? fs.inodes_usage.toFixed(1) + '%' : '-'}</td>`;
+            return `
+                <tr>
+                    <td class="process-name" title="${fs.device}">${this.truncateText(fs.mountpoint, 30)}</td>
+                    <td>${fs.fstype}${fs.read_only ? ' (ro)' : ''}</td>
+                    ${usage}
+                </tr>
+            `;
+        }).join('');
+
+        container.innerHTML = `
+            <table class="processes-table">
+                <thead>
+                    <tr>
+                        <th>Точка монтирования</th>
+                        <th>Тип</th>
+                        <th>Занято</th>
+                        <th>Диск</th>
+                        <th>Inodes</th>
+                    </tr>
+                </thead>
+                <tbody>${rows}</tbody>
+            </table>
+        `;
+    }
+
+    updateCPUCores(cpu) {
+        const physical = cpu.physical_cores ? `${cpu.physical_cores} физ. / ` : '';
+        this.updateElement('cpu-model', `${cpu.model_name || 'ПРОЦЕССОР'} (${physical}${cpu.cores} лог.)`);
+
+        if (this.charts.cores && cpu.per_core) {
+            const chart = this.charts.cores;
+            chart.data.labels = cpu.per_core.map(core =>
+                core.frequency_mhz ? [`CPU ${core.id}`, `${Math.round(core.frequency_mhz)} MHz`] : `CPU ${core.id}`);
+            chart.data.datasets[0].data = cpu.per_core.map(core => core.usage);
+            chart.update('none');
+        }
+
+        const container = document.getElementById('cpu-times');
+        if (!container || !cpu.times) return;
+
+        const names = {
+            user: 'User', nice: 'Nice', system: 'System', idle: 'Idle', iowait: 'IO wait',
+            irq: 'IRQ', softirq: 'SoftIRQ', steal: 'Steal', guest: 'Guest', guest_nice: 'Guest nice'
+        };
+        container.innerHTML = Object.keys(names).map(key => `
+            <div class="cpu-time-row">
+                <span>${names[key]}</span>
+                <span>${(cpu.times[key] || 0).toFixed(1)}%</span>
+            </div>
+        `).join('');
     }
 
     updateNetworkMetrics(data) {
@@ -168,20 +276,32 @@ class SysPulseMonitor {
         this.updateElement('network-status', net.is_online ? '🟢 ONLINE' : '🔴 OFFLINE');
         this.updateElement('network-upload', `${net.current_upload.toFixed(2)} Mb/s`);
         this.updateElement('network-download', `${net.current_download.toFixed(2)} Mb/s`);
-        this.updateElement('network-ping', `${net.ping.toFixed(1)} ms`);
+        this.updateElement('network-ping', net.ping ? `${net.ping.toFixed(1)} ms` : '-');
         this.updateElement('network-ip', net.local_ip || '-');
 
         // Тотальные счетчики из NetworkDetails
         if (data.network_details) {
             const details = data.network_details;
             this.updateElement('network-mac', details.mac_address);
-            this.updateElement('network-public-ip', details.public_ip);
-            this.updateElement('network-total-upload', `${details.total_upload} MB`);
-            this.updateElement('network-total-download', `${details.total_download} MB`);
+            this.updateElement('network-public-ip', details.public_ip || '-');
+            this.updateElement('network-total-upload', this.formatTraffic(details.total_upload));
+            this.updateElement('network-total-download', this.formatTraffic(details.total_download));
         }
 
     }
 
+    // Итоги трафика приходят в байтах
+    formatTraffic(bytes) {
+        const units = ['B', 'KB', 'MB', 'GB', 'TB'];
+        let value = bytes || 0;
+        let unit = 0;
+        while (value >= 1024 && unit < units.length - 1) {
+            value /= 1024;
+            unit++;
+        }
+        return `${value.toFixed(unit === 0 ? 0 : 1)} ${units[unit]}`;
+    }
+
 
 
     updateProcesses(data) {